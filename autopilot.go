@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/mpja69/moving_snakes/ik"
+	"github.com/mpja69/moving_snakes/steering"
+)
+
+// Drive a creature by steering behaviors: every tick the vehicle is put at the creature's head, steer applies
+// the behaviors' forces to it, and the creature heads for the vehicle's target
+func SteeredPath(creature Creature, v *steering.Vehicle, steer func(v *steering.Vehicle)) TargetPath {
+	return func(tick int) ik.Point {
+		v.Pos = creature.chains()[0].First().Pos
+		steer(v)
+		v.Update(TickDuration)
+		return v.Target()
+	}
+}
+
+// Wander around the screen, (the back buffer), on its own
+func RoamPath(creature Creature, seed int64) TargetPath {
+	head := creature.chains()[0].First()
+	v := steering.VehicleNew(head.Pos, 120, 60, seed)
+	v.Radius = head.Radius
+	return SteeredPath(creature, v, func(v *steering.Vehicle) {
+		v.Apply(v.Wander(200, 80, 0.3), 1)
+		v.Apply(v.Contain(ik.Point{}, ik.Point{X: WIDTH * FACTOR, Y: HEIGHT * FACTOR}, 300), 3)
+	})
+}
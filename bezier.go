@@ -1,6 +1,8 @@
 package main
 
-import "github.com/hajimehoshi/ebiten/v2/vector"
+import (
+	"github.com/mpja69/moving_snakes/ik"
+)
 
 func getControlPoints(x1, y1, x2, y2, x3, y3 float64) (float64, float64, float64, float64, float64, float64, float64, float64) {
 	cx1a := x1 + (x2-x1)/3
@@ -16,31 +18,31 @@ func getControlPoints(x1, y1, x2, y2, x3, y3 float64) (float64, float64, float64
 
 // Interpolates a line through the next 2 points: curr and next.
 // The prev point is given to calculate the correct control points
-func interpolate2BezierVertices(path *vector.Path, prev, curr, next Point) {
+func interpolate2BezierVertices(path pathBuilder, prev, curr, next ik.Point) {
 	// cx1a, cy1a, cx1b, cy1b, cx2a, cy2a, cx2b, cy2b := getControlPoints(p1.x, p1.y, p2.x, p2.y, p3.x, p3.y)
-	cx1a := prev.x //+ (curr.x-prev.x)/3
-	cy1a := prev.y //+ (curr.y-prev.y)/3
-	cx1b := curr.x - (next.x-prev.x)/3
-	cy1b := curr.y - (next.y-prev.y)/3
-	cx2a := curr.x + (next.x-prev.x)/3
-	cy2a := curr.y + (next.y-prev.y)/3
-	cx2b := next.x //- (next.x-curr.x)/3
-	cy2b := next.y //- (next.y-curr.y)/3
-	path.CubicTo(float32(cx1a), float32(cy1a), float32(cx1b), float32(cy1b), float32(curr.x), float32(curr.y))
-	path.CubicTo(float32(cx2a), float32(cy2a), float32(cx2b), float32(cy2b), float32(next.x), float32(next.y))
+	cx1a := prev.X //+ (curr.X-prev.X)/3
+	cy1a := prev.Y //+ (curr.Y-prev.Y)/3
+	cx1b := curr.X - (next.X-prev.X)/3
+	cy1b := curr.Y - (next.Y-prev.Y)/3
+	cx2a := curr.X + (next.X-prev.X)/3
+	cy2a := curr.Y + (next.Y-prev.Y)/3
+	cx2b := next.X //- (next.X-curr.X)/3
+	cy2b := next.Y //- (next.Y-curr.Y)/3
+	path.CubicTo(float32(cx1a), float32(cy1a), float32(cx1b), float32(cy1b), float32(curr.X), float32(curr.Y))
+	path.CubicTo(float32(cx2a), float32(cy2a), float32(cx2b), float32(cy2b), float32(next.X), float32(next.Y))
 }
 
 // Interpolates a line through the next 2 points: curr and next.
 // The prev point is given to calculate the correct control points
-func interpolateBezierVertices(path *vector.Path, prev, curr, next Point) {
-	cx1a := prev.x //+ (curr.x-prev.x)/3
-	cy1a := prev.y //+ (curr.y-prev.y)/3
-	cx1b := curr.x - (next.x-prev.x)/3
-	cy1b := curr.y - (next.y-prev.y)/3
-	// cx2a := curr.x + (next.x-prev.x)/3
-	// cy2a := curr.y + (next.y-prev.y)/3
-	// cx2b := next.x //- (next.x-curr.x)/3
-	// cy2b := next.y //- (next.y-curr.y)/3
-	path.CubicTo(float32(cx1a), float32(cy1a), float32(cx1b), float32(cy1b), float32(curr.x), float32(curr.y))
-	// path.CubicTo(float32(cx2a), float32(cy2a), float32(cx2b), float32(cy2b), float32(next.x), float32(next.y))
+func interpolateBezierVertices(path pathBuilder, prev, curr, next ik.Point) {
+	cx1a := prev.X //+ (curr.X-prev.X)/3
+	cy1a := prev.Y //+ (curr.Y-prev.Y)/3
+	cx1b := curr.X - (next.X-prev.X)/3
+	cy1b := curr.Y - (next.Y-prev.Y)/3
+	// cx2a := curr.X + (next.X-prev.X)/3
+	// cy2a := curr.Y + (next.Y-prev.Y)/3
+	// cx2b := next.X //- (next.X-curr.X)/3
+	// cy2b := next.Y //- (next.Y-curr.Y)/3
+	path.CubicTo(float32(cx1a), float32(cy1a), float32(cx1b), float32(cy1b), float32(curr.X), float32(curr.Y))
+	// path.CubicTo(float32(cx2a), float32(cy2a), float32(cx2b), float32(cy2b), float32(next.X), float32(next.Y))
 }
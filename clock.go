@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const maxCatchUp = 8 // Most steps per Advance, so a long stall doesn't freeze the game while it catches up
+
+// Steps a creature in fixed steps of dt seconds, however often it's advanced, (e.g. at 30, 60 or 240 TPS).
+// Drawing blends the joints between the last two steps, so the motion looks smooth between them
+type Clock struct {
+	dt    float64
+	acc   float64 // Time not yet stepped
+	steps int
+	prev  [][]jointState
+	curr  [][]jointState
+}
+
+type jointState struct {
+	pos   ik.Point
+	angle float64
+}
+
+func ClockNew(dt float64) *Clock {
+	return &Clock{dt: dt}
+}
+
+// Steps taken so far
+func (c *Clock) Steps() int {
+	return c.steps
+}
+
+// Let elapsed seconds pass, and step the creature along the path as many times as they fill up, (the path
+// gets the number of the step)
+func (c *Clock) Advance(creature Creature, path TargetPath, elapsed float64) {
+	c.acc = math.Min(c.acc+elapsed, maxCatchUp*c.dt)
+	for c.acc >= c.dt-1e-9 {
+		c.prev = saveJoints(creature, c.prev)
+		creature.update(path(c.steps), c.dt)
+		c.acc -= c.dt
+		c.steps++
+	}
+	c.curr = saveJoints(creature, c.curr)
+}
+
+// How far the time is between the last two steps, 0 to 1
+func (c *Clock) Alpha() float64 {
+	return math.Max(0, math.Min(1, c.acc/c.dt))
+}
+
+// Draw the creature as it is between the last two steps, and then put it back
+func (c *Clock) Draw(creature Creature, draw func()) {
+	if len(c.prev) == 0 {
+		draw()
+		return
+	}
+	alpha := c.Alpha()
+	for i, chain := range creature.chains() {
+		for j, joint := range chain.Joints {
+			a, b := c.prev[i][j], c.curr[i][j]
+			joint.Pos = ik.Point{X: a.pos.X + (b.pos.X-a.pos.X)*alpha, Y: a.pos.Y + (b.pos.Y-a.pos.Y)*alpha}
+			turn := math.Remainder(b.angle-a.angle, 2*math.Pi)
+			joint.Angle = a.angle + turn*alpha
+		}
+	}
+	draw()
+	for i, chain := range creature.chains() {
+		for j, joint := range chain.Joints {
+			joint.Pos, joint.Angle = c.curr[i][j].pos, c.curr[i][j].angle
+		}
+	}
+}
+
+// Copy the joints of all chains, reusing the buffer
+func saveJoints(creature Creature, buf [][]jointState) [][]jointState {
+	chains := creature.chains()
+	if len(buf) != len(chains) {
+		buf = make([][]jointState, len(chains))
+	}
+	for i, chain := range chains {
+		buf[i] = buf[i][:0]
+		for _, joint := range chain.Joints {
+			buf[i] = append(buf[i], jointState{joint.Pos, joint.Angle})
+		}
+	}
+	return buf
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestClockSameMotionAtAnyTPS(t *testing.T) {
+	target := WaypointPath([]ik.Point{{X: 200, Y: 100}}, 1)
+	run := func(tps int) *Lizard {
+		lizard := LizardNew(WIDTH/2, HEIGHT/2)
+		clock := ClockNew(TickDuration)
+		for i := 0; i < 5*tps; i++ {
+			clock.Advance(lizard, target, 1/float64(tps))
+		}
+		if clock.Steps() != 5*TickRate {
+			t.Errorf("ERR: %d TPS: actual: %v,  expected: %v", tps, clock.Steps(), 5*TickRate)
+		}
+		return lizard
+	}
+	expected := run(60)
+	for _, tps := range []int{30, 240} {
+		actual := run(tps)
+		for c, chain := range actual.chains() {
+			for i, j := range chain.Joints {
+				if want := expected.chains()[c].Joints[i].Pos; j.Pos != want {
+					t.Errorf("ERR: %d TPS: chain %d, joint %d: actual: %v,  expected: %v", tps, c, i, j.Pos, want)
+				}
+			}
+		}
+	}
+}
+
+func TestClockDrawsBetweenSteps(t *testing.T) {
+	snake := SnakeNew(WIDTH/2, HEIGHT/2)
+	clock := ClockNew(TickDuration)
+	target := WaypointPath([]ik.Point{{X: 3000, Y: HEIGHT / 2}}, 1)
+	clock.Advance(snake, target, TickDuration)
+	before := snake.chain.First().Pos
+	clock.Advance(snake, target, TickDuration*1.5)
+	after := snake.chain.First().Pos
+
+	var drawn ik.Point
+	clock.Draw(snake, func() { drawn = snake.chain.First().Pos })
+	expected := ik.Point{X: before.X + (after.X-before.X)/2, Y: before.Y + (after.Y-before.Y)/2}
+	if ik.Distance(drawn, expected) > 1e-9 {
+		t.Errorf("ERR: actual: %v,  expected: %v", drawn, expected)
+	}
+	if snake.chain.First().Pos != after {
+		t.Errorf("ERR: actual: %v,  expected: %v", snake.chain.First().Pos, after)
+	}
+}
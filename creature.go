@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// Anything that can be steered towards a target and drawn
+type Creature interface {
+	update(target ik.Point, dt float64) // dt is in seconds
+	draw(screen *ebiten.Image)
+	debugDraw(screen *ebiten.Image)
+	sketch(s *Sketch)          // Add the shapes that draw paints, to export
+	visitState(v stateVisitor) // Save or load the changing state, (see Snapshot)
+	chains() []*ik.Chain       // The body first, then any limbs or fins
+}
+
+// A creature definition, as loaded from a JSON file. See creatures/lizard.json
+type Definition struct {
+	Name   string               `json:"name"`
+	Body   BodyDefinition       `json:"body"`
+	Limbs  []LimbDefinition     `json:"limbs"`
+	Pairs  *LimbPairsDefinition `json:"pairs,omitempty"` // Generated limbs, after the listed ones
+	Speed  float64              `json:"speed"`           // Pixels per second
+	Gait   string               `json:"gait"`
+	Stride float64              `json:"stride,omitempty"` // How far the body moves during one gait cycle. 0 means the default
+	Style  StyleDefinition      `json:"style"`
+}
+
+type BodyDefinition struct {
+	Shape    []int   `json:"shape"`    // Radius of each joint, head first
+	Distance int     `json:"distance"` // Between the joints
+	MaxBend  float64 `json:"maxBend"`  // Radians, at each joint. 0 means no limit
+	// Let the body behind the head swing with inertia, instead of following directly
+	Verlet *VerletDefinition `json:"verlet,omitempty"`
+}
+
+type VerletDefinition struct {
+	Gravity    [2]float64 `json:"gravity"`    // Pixels per second squared
+	Damping    float64    `json:"damping"`    // Share of the velocity lost per second, 0 to 1
+	Stiffness  float64    `json:"stiffness"`  // 0 (a rope) to 1 (a rod)
+	Iterations int        `json:"iterations"` // 0 means the default
+}
+
+type LimbDefinition struct {
+	Anchor   int    `json:"anchor"` // Index of the body joint the limb is attached to
+	Side     string `json:"side"`   // "left" or "right"
+	Front    bool   `json:"front"`  // Fore limbs reach further forward than hind limbs
+	Radii    []int  `json:"radii"`  // Radius of each limb joint, shoulder first
+	Distance int    `json:"distance"`
+	Solver   string `json:"solver"` // "FABRIK" (default), "CCD", "JacobianTranspose" or "DLS"
+	// Where the foot is put down, (see Limb.SetFoothold). Without an angle, the default for fore or hind limbs,
+	// (0 points straight ahead). Without a reach, or 0, the default
+	FootAngle *float64 `json:"footAngle,omitempty"`
+	FootReach float64  `json:"footReach,omitempty"`
+	// How far from its body joint the foot may get before it steps. 0 means the default, (past the limb's reach)
+	StepDistance float64 `json:"stepDistance,omitempty"`
+}
+
+// A pair of equal limbs, (left and right), on every k-th body joint. See creatures/centipede.json
+type LimbPairsDefinition struct {
+	First    int    `json:"first"` // Body joint of the first pair
+	Every    int    `json:"every"` // Body joints from one pair to the next
+	Count    int    `json:"count"`
+	Radii    []int  `json:"radii"` // Radius of each limb joint, shoulder first. As many joints as needed
+	Distance int    `json:"distance"`
+	Solver   string `json:"solver"`
+	// Radians from the heading out to the foothold, of the first and of the last pair, (the others in between).
+	// Without a fan, every pair reaches straight out to the side
+	Fan   *[2]float64 `json:"fan,omitempty"`
+	Reach float64     `json:"reach"` // Share of the limb's length out to the foothold. 0 means 0.5
+}
+
+type StyleDefinition struct {
+	Fill         string  `json:"fill"`    // "#RRGGBB"
+	Outline      string  `json:"outline"` // "#RRGGBB"
+	OutlineWidth float64 `json:"outlineWidth"`
+	LimbWidth    float64 `json:"limbWidth"`
+	EyeRadius    float64 `json:"eyeRadius"`
+}
+
+// How a creature is drawn
+type Style struct {
+	Fill         color.RGBA
+	Outline      color.RGBA
+	OutlineWidth float64
+	LimbWidth    float64
+	EyeRadius    float64
+}
+
+// Points to the field of a definition that is wrong
+type DefinitionError struct {
+	Field string
+	Msg   string
+}
+
+func (e *DefinitionError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+func defErr(field, format string, args ...any) error {
+	return &DefinitionError{Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
+// The lizard, as it has always looked
+var LizardDefinition = Definition{
+	Name: "lizard",
+	Body: BodyDefinition{
+		Shape:    []int{52, 58, 40, 60, 68, 71, 65, 50, 28, 15, 11, 9, 7, 7, 7},
+		Distance: 64,
+		MaxBend:  spineMaxBend,
+	},
+	Limbs: []LimbDefinition{
+		{Anchor: 3, Side: "right", Front: true, Radii: []int{20, 40, 30}, Distance: 40},
+		{Anchor: 7, Side: "left", Front: false, Radii: []int{20, 40, 30}, Distance: 40},
+		{Anchor: 3, Side: "left", Front: true, Radii: []int{20, 40, 30}, Distance: 40},
+		{Anchor: 7, Side: "right", Front: false, Radii: []int{20, 40, 30}, Distance: 40},
+	},
+	Speed: 120,
+	Gait:  "trot",
+	Style: StyleDefinition{Fill: "#58857A", Outline: "#FFFFFF", OutlineWidth: 3, LimbWidth: 32, EyeRadius: 10},
+}
+
+func LoadDefinition(path string) (*Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	def, err := ParseDefinition(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return def, nil
+}
+
+// Decode and validate a definition. Unknown fields are errors, to catch typos
+func ParseDefinition(r io.Reader) (*Definition, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var def Definition
+	if err := dec.Decode(&def); err != nil {
+		return nil, err
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+func (d *Definition) Validate() error {
+	if len(d.Body.Shape) < 3 {
+		return defErr("body.shape", "needs at least 3 joints, has %d", len(d.Body.Shape))
+	}
+	for i, r := range d.Body.Shape {
+		if r <= 0 {
+			return defErr(fmt.Sprintf("body.shape[%d]", i), "radius must be positive, is %d", r)
+		}
+	}
+	if d.Body.Distance <= 0 {
+		return defErr("body.distance", "must be positive, is %d", d.Body.Distance)
+	}
+	if d.Body.MaxBend < 0 {
+		return defErr("body.maxBend", "must not be negative, is %g", d.Body.MaxBend)
+	}
+	if v := d.Body.Verlet; v != nil {
+		if v.Damping < 0 || v.Damping > 1 {
+			return defErr("body.verlet.damping", "must be 0 to 1, is %g", v.Damping)
+		}
+		if v.Stiffness < 0 || v.Stiffness > 1 {
+			return defErr("body.verlet.stiffness", "must be 0 to 1, is %g", v.Stiffness)
+		}
+		if v.Iterations < 0 {
+			return defErr("body.verlet.iterations", "must not be negative, is %d", v.Iterations)
+		}
+	}
+	for i, l := range d.Limbs {
+		if err := l.validate(fmt.Sprintf("limbs[%d]", i), len(d.Body.Shape)); err != nil {
+			return err
+		}
+	}
+	if p := d.Pairs; p != nil {
+		if p.Every <= 0 {
+			return defErr("pairs.every", "must be positive, is %d", p.Every)
+		}
+		if p.Count <= 0 {
+			return defErr("pairs.count", "must be positive, is %d", p.Count)
+		}
+		if last := p.First + (p.Count-1)*p.Every; p.First < 0 || last >= len(d.Body.Shape) {
+			return defErr("pairs", "joints %d to %d are outside the body, (0 to %d)", p.First, last, len(d.Body.Shape)-1)
+		}
+		if p.Fan != nil {
+			for i, a := range p.Fan {
+				if a < 0 || a > math.Pi {
+					return defErr(fmt.Sprintf("pairs.fan[%d]", i), "must be 0 to pi, is %g", a)
+				}
+			}
+		}
+		if p.Reach < 0 {
+			return defErr("pairs.reach", "must not be negative, is %g", p.Reach)
+		}
+		l := LimbDefinition{Side: "left", Radii: p.Radii, Distance: p.Distance, Solver: p.Solver}
+		if err := l.validate("pairs", len(d.Body.Shape)); err != nil {
+			return err
+		}
+	}
+	if d.Stride < 0 {
+		return defErr("stride", "must not be negative, is %g", d.Stride)
+	}
+	if d.Speed < 0 {
+		return defErr("speed", "must not be negative, is %g", d.Speed)
+	}
+	if d.Gait != "" {
+		if _, err := ParseGait(d.Gait); err != nil {
+			return defErr("gait", "%v", err)
+		}
+	}
+	if _, err := d.Style.parse(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (l LimbDefinition) validate(field string, bodyLen int) error {
+	if l.Anchor < 0 || l.Anchor >= bodyLen {
+		return defErr(field+".anchor", "%d is outside the body, (0 to %d)", l.Anchor, bodyLen-1)
+	}
+	if l.Side != "left" && l.Side != "right" {
+		return defErr(field+".side", "must be \"left\" or \"right\", is %q", l.Side)
+	}
+	if len(l.Radii) < 3 {
+		return defErr(field+".radii", "needs at least 3 joints, (shoulder, elbow and foot), has %d", len(l.Radii))
+	}
+	for j, r := range l.Radii {
+		if r <= 0 {
+			return defErr(fmt.Sprintf("%s.radii[%d]", field, j), "radius must be positive, is %d", r)
+		}
+	}
+	if l.Distance <= 0 {
+		return defErr(field+".distance", "must be positive, is %d", l.Distance)
+	}
+	if _, err := parseSolver(l.Solver); err != nil {
+		return defErr(field+".solver", "%v", err)
+	}
+	if a := l.FootAngle; a != nil && (*a < 0 || *a > math.Pi) {
+		return defErr(field+".footAngle", "must be 0 to pi, is %g", *a)
+	}
+	if l.FootReach < 0 {
+		return defErr(field+".footReach", "must not be negative, is %g", l.FootReach)
+	}
+	if l.StepDistance < 0 {
+		return defErr(field+".stepDistance", "must not be negative, is %g", l.StepDistance)
+	}
+	return nil
+}
+
+// The listed limbs, followed by the generated pairs, head first and left before right
+func (d *Definition) allLimbs() []LimbDefinition {
+	limbs := append([]LimbDefinition{}, d.Limbs...)
+	p := d.Pairs
+	if p == nil {
+		return limbs
+	}
+	reach := p.Reach
+	if reach == 0 {
+		reach = 0.5
+	}
+	fan := [2]float64{math.Pi / 2, math.Pi / 2}
+	if p.Fan != nil {
+		fan = *p.Fan
+	}
+	for i := 0; i < p.Count; i++ {
+		t := 0.0
+		if p.Count > 1 {
+			t = float64(i) / float64(p.Count-1)
+		}
+		angle := fan[0] + (fan[1]-fan[0])*t
+		// Thin bodies can't hide a stretched leg, so step as soon as the foot is out of reach
+		anchor := p.First + i*p.Every
+		stepAt := float64((len(p.Radii)-1)*p.Distance + d.Body.Shape[anchor])
+		for _, side := range []string{"left", "right"} {
+			limbs = append(limbs, LimbDefinition{
+				Anchor: anchor, Side: side, Front: t < 0.5, Radii: p.Radii, Distance: p.Distance,
+				Solver: p.Solver, FootAngle: &angle, FootReach: reach, StepDistance: stepAt,
+			})
+		}
+	}
+	return limbs
+}
+
+func (v *VerletDefinition) options() *ik.VerletOptions {
+	opts := ik.VerletOptions{Gravity: ik.Point{X: v.Gravity[0], Y: v.Gravity[1]}, Damping: v.Damping, Stiffness: v.Stiffness, Iterations: v.Iterations}
+	if opts.Iterations == 0 {
+		opts.Iterations = ik.DefaultVerletOptions.Iterations
+	}
+	return &opts
+}
+
+func parseSolver(name string) (ik.Solver, error) {
+	for _, s := range []ik.Solver{ik.SolverFABRIK, ik.SolverCCD, ik.SolverJacobianTranspose, ik.SolverDLS} {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	if name == "" {
+		return ik.SolverFABRIK, nil
+	}
+	return 0, fmt.Errorf("unknown solver %q", name)
+}
+
+func (s StyleDefinition) parse() (Style, error) {
+	fill, err := parseColor(s.Fill)
+	if err != nil {
+		return Style{}, defErr("style.fill", "%v", err)
+	}
+	outline, err := parseColor(s.Outline)
+	if err != nil {
+		return Style{}, defErr("style.outline", "%v", err)
+	}
+	sizes := []struct {
+		field string
+		v     float64
+	}{{"outlineWidth", s.OutlineWidth}, {"limbWidth", s.LimbWidth}, {"eyeRadius", s.EyeRadius}}
+	for _, size := range sizes {
+		if size.v < 0 {
+			return Style{}, defErr("style."+size.field, "must not be negative, is %g", size.v)
+		}
+	}
+	return Style{Fill: fill, Outline: outline, OutlineWidth: s.OutlineWidth, LimbWidth: s.LimbWidth, EyeRadius: s.EyeRadius}, nil
+}
+
+// Parse "#RRGGBB"
+func parseColor(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("want a color like \"#58857A\", got %q", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("want a color like \"#58857A\", got %q", s)
+	}
+	return color.RGBA{r, g, b, 255}, nil
+}
+
+// Build a lizard like creature from a definition, with the head at x, y
+func LizardFromDefinition(def *Definition, x, y int) (*Lizard, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	style, _ := def.Style.parse()
+
+	chain := ik.ChainNew(def.Body.Shape, x, y, def.Body.Distance)
+	if def.Body.MaxBend > 0 {
+		for _, j := range chain.Joints[1:] {
+			j.SetAngleLimits(-def.Body.MaxBend, def.Body.MaxBend)
+		}
+	}
+
+	if v := def.Body.Verlet; v != nil {
+		chain.SetVerlet(v.options())
+	}
+
+	limbDefs := def.allLimbs()
+	limbs := make([]*Limb, 0, len(limbDefs))
+	for _, ld := range limbDefs {
+		l := LimbNew(chain.Joints[ld.Anchor], ld.Radii, ld.Distance, ld.Side == "right", ld.Front)
+		solver, _ := parseSolver(ld.Solver)
+		l.SetSolver(solver)
+		angle, reach := l.footAngle, l.footReach
+		if ld.FootAngle != nil {
+			angle = *ld.FootAngle
+		}
+		if ld.FootReach > 0 {
+			reach = ld.FootReach
+		}
+		l.SetFoothold(angle, reach)
+		if ld.StepDistance > 0 {
+			l.SetStepDistance(ld.StepDistance)
+		}
+		limbs = append(limbs, l)
+	}
+
+	pattern := GaitTrot
+	if def.Gait != "" {
+		pattern, _ = ParseGait(def.Gait)
+	}
+	stride := def.Stride
+	if stride == 0 {
+		stride = gaitStride
+	}
+	gait := GaitNew(limbs, pattern, stride)
+	return &Lizard{chain: chain, limbs: limbs, gait: gait, style: style, vertices: []ebiten.Vertex{}, indices: []uint16{}, speed: def.Speed}, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadDefinition(t *testing.T) {
+	def, err := LoadDefinition("creatures/lizard.json")
+	if err != nil {
+		t.Fatalf("ERR: %v", err)
+	}
+	if !reflect.DeepEqual(*def, LizardDefinition) {
+		t.Errorf("ERR: actual: %+v,  expected: %+v", *def, LizardDefinition)
+	}
+	if _, err := LizardFromDefinition(def, 0, 0); err != nil {
+		t.Errorf("ERR: %v", err)
+	}
+}
+
+func TestDefinitionErrors(t *testing.T) {
+	tests := []struct {
+		edit  func(d *Definition)
+		field string
+	}{
+		{func(d *Definition) { d.Body.Shape = d.Body.Shape[:2] }, "body.shape"},
+		{func(d *Definition) { d.Body.Shape = []int{10, -1, 10} }, "body.shape[1]"},
+		{func(d *Definition) { d.Limbs = append(d.Limbs, LimbDefinition{Anchor: 15}) }, "limbs[4].anchor"},
+		{func(d *Definition) { d.Limbs = []LimbDefinition{{Anchor: 1, Side: "up"}} }, "limbs[0].side"},
+		{func(d *Definition) {
+			d.Limbs = []LimbDefinition{{Anchor: 1, Side: "left", Radii: []int{1, 2, 3}, Distance: 5, Solver: "magic"}}
+		}, "limbs[0].solver"},
+		{func(d *Definition) { d.Body.Verlet = &VerletDefinition{Damping: 2} }, "body.verlet.damping"},
+		{func(d *Definition) { d.Gait = "hop" }, "gait"},
+		{func(d *Definition) { d.Pairs = &LimbPairsDefinition{First: 1, Every: 0, Count: 2} }, "pairs.every"},
+		{func(d *Definition) { d.Pairs = &LimbPairsDefinition{First: 1, Every: 2, Count: 8} }, "pairs"},
+		{func(d *Definition) {
+			d.Pairs = &LimbPairsDefinition{First: 1, Every: 1, Count: 2, Radii: []int{1, 2}, Distance: 5}
+		}, "pairs.radii"},
+		{func(d *Definition) { d.Style.Fill = "green" }, "style.fill"},
+	}
+	for _, test := range tests {
+		def := LizardDefinition
+		def.Body.Shape = append([]int{}, def.Body.Shape...)
+		def.Limbs = append([]LimbDefinition{}, def.Limbs...)
+		test.edit(&def)
+
+		var defErr *DefinitionError
+		err := def.Validate()
+		if !errors.As(err, &defErr) || defErr.Field != test.field {
+			t.Errorf("ERR: actual: %v,  expected: an error in %v", err, test.field)
+		}
+	}
+}
+
+func TestParseDefinitionUnknownField(t *testing.T) {
+	_, err := ParseDefinition(strings.NewReader(`{"name": "x", "legs": []}`))
+	if err == nil || !strings.Contains(err.Error(), "legs") {
+		t.Errorf("ERR: actual: %v,  expected: an error about legs", err)
+	}
+}
+
+func TestLimbPairs(t *testing.T) {
+	tests := []struct {
+		file   string
+		limbs  int
+		joints int
+	}{
+		{"creatures/centipede.json", 30, 3},
+		{"creatures/spider.json", 8, 5},
+	}
+	for _, test := range tests {
+		def, err := LoadDefinition(test.file)
+		if err != nil {
+			t.Fatalf("ERR: %v", err)
+		}
+		c, err := LizardFromDefinition(def, 0, 0)
+		if err != nil {
+			t.Fatalf("ERR: %v", err)
+		}
+		if len(c.limbs) != test.limbs {
+			t.Errorf("ERR: %s: actual: %v,  expected: %v", test.file, len(c.limbs), test.limbs)
+		}
+		for i, l := range c.limbs {
+			if len(l.chain.Joints) != test.joints {
+				t.Errorf("ERR: %s: limb %d: actual: %v,  expected: %v", test.file, i, len(l.chain.Joints), test.joints)
+			}
+			// The wave runs from the head to the tail, with the right side half a cycle behind the left
+			pair := i / 2
+			want := math.Mod(float64(pair)*waveLag+0.5*float64(i%2), 1)
+			if math.Abs(c.gait.offsets[i]-want) > 1e-9 {
+				t.Errorf("ERR: %s: offset %d: actual: %v,  expected: %v", test.file, i, c.gait.offsets[i], want)
+			}
+		}
+	}
+}
+
+// A fan runs evenly from its first angle to its last, (0 too, straight ahead), and without one every pair
+// reaches out to the side. A single limb's angle of 0 is kept as well
+func TestLimbPairsFan(t *testing.T) {
+	def, err := LoadDefinition("creatures/spider.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs := *def.Pairs
+	def.Pairs = &pairs
+	tests := []struct {
+		fan         *[2]float64
+		first, last float64
+	}{
+		{&[2]float64{0, 1.2}, 0, 1.2},
+		{&[2]float64{2.4, 0}, 2.4, 0},
+		{nil, math.Pi / 2, math.Pi / 2},
+	}
+	for _, test := range tests {
+		pairs.Fan = test.fan
+		c, err := LizardFromDefinition(def, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, l := range c.limbs {
+			pair := float64(i / 2)
+			want := test.first + (test.last-test.first)*pair/float64(pairs.Count-1)
+			if math.Abs(l.footAngle-want) > 1e-9 {
+				t.Errorf("ERR: %v: limb %d: actual: %v,  expected: %v", test.fan, i, l.footAngle, want)
+			}
+		}
+	}
+
+	lizard := LizardDefinition
+	lizard.Limbs = append([]LimbDefinition{}, lizard.Limbs...)
+	ahead := 0.0
+	lizard.Limbs[0].FootAngle = &ahead
+	c, err := LizardFromDefinition(&lizard, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.limbs[0].footAngle != 0 || c.limbs[1].footAngle == 0 {
+		t.Errorf("ERR: actual: %v %v,  expected: 0, and the default", c.limbs[0].footAngle, c.limbs[1].footAngle)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const (
+	fishThrust       = 54   // Forward push, (pixels per second), per radian of tail swing
+	fishForwardDrag  = 0.4  // Share of the forward speed kept after a second
+	fishSidewaysDrag = 1e-6 // Share of the sideways speed kept after a second
+	fishTurnRate     = 2.4  // Share of the heading error corrected per second, at full speed
+	fishTailBeat     = 7.2  // Radians of the tail beat per second, at full effort
+	fishTailSwing    = 0.45 // Largest bend of the tail, at full effort
+)
+
+// A fish: the tail beats from side to side, and every beat pushes the fish forward. Without beats it glides
+// and slows down. The fins are drawn from the shape of the spine
+type Fish struct {
+	chain    *ik.Chain
+	velocity ik.Point // Pixels per second
+	heading  float64
+	effort   float64 // 0 to 1: how hard the tail beats
+	phase    float64 // Of the tail beat
+	tailBend float64 // At the last joint
+	style    Style
+	// To draw
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+func FishNew(x, y int) *Fish {
+	bodyShape := []int{34, 40, 42, 41, 38, 32, 25, 19, 14, 9}
+	chain := ik.ChainNew(bodyShape, x, y, 32)
+	style := Style{
+		Fill:         color.RGBA{0x58, 0x7A, 0x85, 255},
+		Outline:      color.RGBA{255, 255, 255, 255},
+		OutlineWidth: 3,
+		EyeRadius:    8,
+	}
+	return &Fish{chain: chain, style: style}
+}
+
+func (f *Fish) update(target ik.Point, dt float64) {
+	head := f.chain.First()
+
+	// Beat the tail harder the further away the target is
+	want := math.Min(1, ik.Distance(head.Pos, target)/400)
+	if ik.Distance(head.Pos, target) < head.Radius*2 {
+		want = 0
+	}
+	f.effort += (want - f.effort) * math.Min(1, 3*dt)
+
+	// The tail beat: the push comes from how fast the tail swings
+	f.phase += fishTailBeat * (0.5 + f.effort) * dt
+	prevBend := f.tailBend
+	f.tailBend = f.swingAt(len(f.chain.Joints) - 1)
+	thrust := fishThrust * math.Abs(f.tailBend-prevBend)
+
+	// Turn towards the target, faster when moving
+	delta := head.Pos.Sub(target)
+	turn := math.Atan2(-delta.Y, -delta.X) - f.heading
+	for turn < -math.Pi {
+		turn += 2 * math.Pi
+	}
+	for turn > math.Pi {
+		turn -= 2 * math.Pi
+	}
+	f.heading += turn * math.Min(1, fishTurnRate*dt*math.Min(1, 0.2+f.velocity.Mag()/120))
+
+	// Water resists moving sideways much more than moving forward
+	forward := ik.Point{X: math.Cos(f.heading), Y: math.Sin(f.heading)}
+	along := f.velocity.X*forward.X + f.velocity.Y*forward.Y
+	sideways := f.velocity.Sub(ik.Point{X: forward.X * along, Y: forward.Y * along})
+	along = (along + thrust) * math.Pow(fishForwardDrag, dt)
+	keep := math.Pow(fishSidewaysDrag, dt)
+	f.velocity = ik.Point{X: forward.X * along, Y: forward.Y * along}.Add(ik.Point{X: sideways.X * keep, Y: sideways.Y * keep})
+
+	head.Pos = head.Pos.Add(ik.Point{X: f.velocity.X * dt, Y: f.velocity.Y * dt})
+	head.Angle = f.heading
+	f.swing()
+}
+
+// Follow the head, and bend the rear half of the body with the tail beat
+func (f *Fish) swing() {
+	joints := f.chain.Joints
+	for i := 1; i < len(joints); i++ {
+		prev := joints[i-1]
+		curr := joints[i]
+		curr.DirectlyFollow(prev.Pos)
+		if bend := f.swingAt(i); bend != 0 && i >= 2 {
+			dir := joints[i-2].Pos.Angle(prev.Pos) + math.Pi + bend
+			curr.Pos = ik.Point{X: prev.Pos.X + math.Cos(dir)*prev.Distance, Y: prev.Pos.Y + math.Sin(dir)*prev.Distance}
+			curr.Angle = prev.Pos.Angle(curr.Pos)
+		}
+	}
+}
+
+// The bend of the tail beat at joint i: a wave running backwards through the rear half, growing towards the tail
+func (f *Fish) swingAt(i int) float64 {
+	n := len(f.chain.Joints)
+	rear := n / 2
+	if i <= rear {
+		return 0
+	}
+	weight := float64(i-rear) / float64(n-1-rear)
+	return fishTailSwing * f.effort * weight * math.Sin(f.phase-float64(i-rear)*0.6)
+}
+
+// How much the spine turns at joint i, (positive is clockwise on screen)
+func (f *Fish) bend(i int) float64 {
+	j := f.chain.Joints
+	if i <= 0 || i >= len(j)-1 {
+		return 0
+	}
+	a := math.Atan2(j[i].Pos.Y-j[i-1].Pos.Y, j[i].Pos.X-j[i-1].Pos.X)
+	b := math.Atan2(j[i+1].Pos.Y-j[i].Pos.Y, j[i+1].Pos.X-j[i].Pos.X)
+	d := b - a
+	return math.Atan2(math.Sin(d), math.Cos(d))
+}
+
+func (f *Fish) chains() []*ik.Chain {
+	return []*ik.Chain{f.chain}
+}
+
+func (f *Fish) draw(screen *ebiten.Image) {
+	// Under the body: the pectoral fins and the tail fin
+	fins := vector.Path{}
+	f.pectoralFins(&fins)
+	f.tailFin(&fins)
+	f.vertices, f.indices = drawPath(screen, &fins, f.style, f.vertices, f.indices)
+
+	path := chainPath(f.chain)
+	f.vertices, f.indices = drawPath(screen, path, f.style, f.vertices, f.indices)
+
+	// On top of the body: the dorsal fin
+	dorsal := vector.Path{}
+	f.dorsalFin(&dorsal)
+	f.vertices, f.indices = drawPath(screen, &dorsal, f.style, f.vertices, f.indices)
+
+	drawEyes(screen, f.chain.First(), f.style)
+}
+
+func (f *Fish) sketch(s *Sketch) {
+	fins := Outline{}
+	f.pectoralFins(&fins)
+	f.tailFin(&fins)
+	s.fill(fins, f.style)
+	s.body(f.chain, f.style)
+	dorsal := Outline{}
+	f.dorsalFin(&dorsal)
+	s.fill(dorsal, f.style)
+	s.eyes(f.chain.First(), f.style)
+}
+
+func (f *Fish) visitState(v stateVisitor) {
+	visitChain(v, "body", f.chain)
+	v.point("velocity", &f.velocity)
+	v.float("heading", &f.heading)
+	v.float("effort", &f.effort)
+	v.float("phase", &f.phase)
+	v.float("tailBend", &f.tailBend)
+}
+
+// Two fins behind the head, swept back. The fin on the inside of a turn tucks in, the outside one flares out
+func (f *Fish) pectoralFins(path pathBuilder) {
+	j := f.chain.Joints[2]
+	flex := f.bend(2) * 3
+	back := j.Angle + math.Pi
+	right := j.AdjustedPos(math.Pi/2, -6)
+	left := j.AdjustedPos(-math.Pi/2, -6)
+	appendEllipse(path, right, 36, 14, back-math.Pi/4-flex)
+	appendEllipse(path, left, 36, 14, back+math.Pi/4-flex)
+}
+
+// A forked fin at the end of the tail. Each lobe follows the angle of the last joints
+func (f *Fish) tailFin(path pathBuilder) {
+	n := len(f.chain.Joints)
+	last := f.chain.Joints[n-1]
+	before := f.chain.Joints[n-2]
+	dir := last.Angle + math.Pi
+	curl := before.Angle - last.Angle
+	curl = math.Atan2(math.Sin(curl), math.Cos(curl))
+
+	for _, side := range []float64{-1, 1} {
+		lobe := dir + side*0.45 - curl*1.5
+		tip := ik.Point{X: last.Pos.X + math.Cos(lobe)*52, Y: last.Pos.Y + math.Sin(lobe)*52}
+		mid := ik.Point{X: last.Pos.X + math.Cos(lobe-side*0.25)*30, Y: last.Pos.Y + math.Sin(lobe-side*0.25)*30}
+		if side < 0 {
+			path.MoveTo(float32(last.Pos.X), float32(last.Pos.Y))
+		}
+		path.QuadTo(float32(mid.X), float32(mid.Y), float32(tip.X), float32(tip.Y))
+		path.LineTo(float32(last.Pos.X+math.Cos(dir)*20), float32(last.Pos.Y+math.Sin(dir)*20))
+	}
+	path.Close()
+}
+
+// A thin fin along the middle of the back, bulging to the outside of the spine's bend
+func (f *Fish) dorsalFin(path pathBuilder) {
+	a := f.chain.Joints[3].Pos
+	b := f.chain.Joints[6].Pos
+	bend := f.bend(4) + f.bend(5)
+	mid := f.chain.Joints[4].Pos.Add(f.chain.Joints[5].Pos)
+	mid = ik.Point{X: mid.X / 2, Y: mid.Y / 2}
+	perp := ik.Point{X: -(b.Y - a.Y), Y: b.X - a.X}.SetMag(-bend * 120)
+	ctrl := mid.Add(perp)
+
+	path.MoveTo(float32(a.X), float32(a.Y))
+	path.QuadTo(float32(ctrl.X), float32(ctrl.Y), float32(b.X), float32(b.Y))
+	path.QuadTo(float32(mid.X), float32(mid.Y), float32(a.X), float32(a.Y))
+	path.Close()
+}
+
+// Append a closed, rotated ellipse to the path, (four cubic Bezier arcs)
+func appendEllipse(path pathBuilder, center ik.Point, rx, ry, angle float64) {
+	const k = 0.5522847498 // Control point distance for a quarter circle
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	pt := func(x, y float64) (float32, float32) {
+		return float32(center.X + x*cos - y*sin), float32(center.Y + x*sin + y*cos)
+	}
+	path.MoveTo(pt(rx, 0))
+	x1, y1 := pt(rx, ry*k)
+	x2, y2 := pt(rx*k, ry)
+	x3, y3 := pt(0, ry)
+	path.CubicTo(x1, y1, x2, y2, x3, y3)
+	x1, y1 = pt(-rx*k, ry)
+	x2, y2 = pt(-rx, ry*k)
+	x3, y3 = pt(-rx, 0)
+	path.CubicTo(x1, y1, x2, y2, x3, y3)
+	x1, y1 = pt(-rx, -ry*k)
+	x2, y2 = pt(-rx*k, -ry)
+	x3, y3 = pt(0, -ry)
+	path.CubicTo(x1, y1, x2, y2, x3, y3)
+	x1, y1 = pt(rx*k, -ry)
+	x2, y2 = pt(rx, -ry*k)
+	x3, y3 = pt(rx, 0)
+	path.CubicTo(x1, y1, x2, y2, x3, y3)
+	path.Close()
+}
+
+func (f *Fish) debugDraw(screen *ebiten.Image) {
+	for _, j := range f.chain.Joints {
+		drawJointCircle(screen, j)
+	}
+}
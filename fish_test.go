@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestFishSwimsAndGlides(t *testing.T) {
+	fish := FishNew(WIDTH/2, HEIGHT/2)
+	far := ik.Point{X: 3000, Y: HEIGHT / 2}
+	for i := 0; i < 300; i++ {
+		fish.update(far, TickDuration)
+	}
+	swimming := fish.velocity.Mag()
+	if swimming < 60 {
+		t.Errorf("ERR: swimming speed: actual: %v,  expected: at least %v", swimming, 60)
+	}
+
+	// With the target at the head the tail stops beating, and the fish glides, slower and slower
+	prev := math.Inf(1)
+	for i := 0; i < 400; i++ {
+		fish.update(fish.chain.First().Pos, TickDuration)
+		speed := fish.velocity.Mag()
+		if i >= 100 && speed > prev+1e-9 {
+			t.Fatalf("ERR: tick %d: actual: %v,  expected: at most %v", i, speed, prev)
+		}
+		prev = speed
+	}
+	if prev > 0.1*swimming {
+		t.Errorf("ERR: gliding speed: actual: %v,  expected: below %v", prev, 0.1*swimming)
+	}
+
+	joints := fish.chain.Joints
+	for i := 1; i < len(joints); i++ {
+		d := ik.Distance(joints[i-1].Pos, joints[i].Pos)
+		if math.Abs(d-joints[i-1].Distance) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, joints[i-1].Distance)
+		}
+	}
+}
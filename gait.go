@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// The order the legs step in, over one gait cycle
+type GaitPattern int
+
+const (
+	GaitTrot    GaitPattern = iota // Diagonal pairs together
+	GaitWalk                       // One leg at a time: hind, opposite fore, other hind, its fore
+	GaitLateral                    // One leg at a time: hind, same side fore, other hind, its fore
+	GaitGallop                     // Hind legs close together, then the fore legs
+	GaitWave                       // A wave running from the head to the tail, left and right half a cycle apart
+)
+
+const (
+	waveLag    = 1.0 / 6 // Share of the cycle between neighbouring pairs, in the wave gait
+	waveWindow = 0.35
+)
+
+var gaitNames = map[string]GaitPattern{
+	"trot":    GaitTrot,
+	"walk":    GaitWalk,
+	"lateral": GaitLateral,
+	"gallop":  GaitGallop,
+	"wave":    GaitWave,
+}
+
+func ParseGait(name string) (GaitPattern, error) {
+	if p, ok := gaitNames[name]; ok {
+		return p, nil
+	}
+	return 0, fmt.Errorf("unknown gait %q", name)
+}
+
+func (p GaitPattern) String() string {
+	for name, q := range gaitNames {
+		if q == p {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// When in the cycle each leg may lift, (left fore, right fore, left hind, right hind), and for how long
+func (p GaitPattern) phases() (lf, rf, lh, rh, window float64) {
+	switch p {
+	case GaitWalk:
+		return 0.75, 0.25, 0, 0.5, 0.25
+	case GaitLateral:
+		return 0.25, 0.75, 0, 0.5, 0.25
+	case GaitGallop:
+		return 0.5, 0.6, 0, 0.1, 0.4
+	case GaitWave:
+		return 0, 0.5, 0, 0.5, waveWindow // Plus the lag of each pair, (see SetPattern)
+	}
+	return 0, 0.5, 0.5, 0, 0.5
+}
+
+// Coordinates the limbs: each one only steps in its part of the gait cycle, and never while its pair,
+// (the opposite leg on the same body joint), is in the air
+type Gait struct {
+	limbs   []*Limb
+	pattern GaitPattern
+	stride  float64 // How far the body moves during one cycle
+	phase   float64 // 0 to 1
+	offsets []float64
+	window  float64
+	pairs   []int
+}
+
+func GaitNew(limbs []*Limb, pattern GaitPattern, stride float64) *Gait {
+	g := &Gait{limbs: limbs, stride: stride}
+	g.pairs = make([]int, len(limbs))
+	for i, l := range limbs {
+		g.pairs[i] = -1
+		for j, m := range limbs {
+			if i != j && l.anchorJoint == m.anchorJoint && l.rightSide != m.rightSide {
+				g.pairs[i] = j
+			}
+		}
+	}
+	g.SetPattern(pattern)
+	return g
+}
+
+func (g *Gait) SetPattern(pattern GaitPattern) {
+	g.pattern = pattern
+	lf, rf, lh, rh, window := pattern.phases()
+	g.window = window
+	g.offsets = make([]float64, len(g.limbs))
+	anchors := []*ik.Joint{} // In the order of the limbs, which is head first for generated pairs
+	for i, l := range g.limbs {
+		if pattern == GaitWave {
+			pair := slices.Index(anchors, l.anchorJoint)
+			if pair < 0 {
+				pair = len(anchors)
+				anchors = append(anchors, l.anchorJoint)
+			}
+			g.offsets[i] = math.Mod(float64(pair)*waveLag, 1)
+			if l.rightSide {
+				g.offsets[i] = math.Mod(g.offsets[i]+rf, 1)
+			}
+			continue
+		}
+		switch {
+		case l.frontSide && !l.rightSide:
+			g.offsets[i] = lf
+		case l.frontSide && l.rightSide:
+			g.offsets[i] = rf
+		case !l.rightSide:
+			g.offsets[i] = lh
+		default:
+			g.offsets[i] = rh
+		}
+	}
+}
+
+// Advance the cycle by how far the body moved, (so the cadence follows the speed), and update the limbs
+func (g *Gait) update(speed, dt float64) {
+	g.phase = math.Mod(g.phase+speed*dt/g.stride, 1)
+	for i, l := range g.limbs {
+		l.update(g.mayStep(i), dt)
+	}
+}
+
+// True if limb i is in its part of the cycle, and its pair is on the ground
+func (g *Gait) mayStep(i int) bool {
+	if p := g.pairs[i]; p >= 0 && !g.limbs[p].step.Planted() {
+		return false
+	}
+	return math.Mod(g.phase-g.offsets[i]+1, 1) < g.window
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/mpja69/moving_snakes/ik"
+	"github.com/mpja69/moving_snakes/terrain"
+)
+
+// How often the way to the goal is searched again, even if the goal hasn't moved
+const replanTicks = TickRate
+
+// A terrain, as loaded from a JSON file. See worlds/garden.json
+type TerrainDefinition struct {
+	Size       [2]float64        `json:"size"` // Of the area paths are searched in, from 0,0
+	Cell       float64           `json:"cell"` // Of the path finding grid. 0 means 40
+	Obstacles  ShapesDefinition  `json:"obstacles"`
+	Unwalkable *ShapesDefinition `json:"unwalkable,omitempty"`
+}
+
+type ShapesDefinition struct {
+	Circles  []CircleDefinition `json:"circles,omitempty"`
+	Polygons [][][2]float64     `json:"polygons,omitempty"` // Corners, in order
+	Walls    []WallDefinition   `json:"walls,omitempty"`
+}
+
+type CircleDefinition struct {
+	Center [2]float64 `json:"center"`
+	Radius float64    `json:"radius"`
+}
+
+type WallDefinition struct {
+	From  [2]float64 `json:"from"`
+	To    [2]float64 `json:"to"`
+	Width float64    `json:"width"`
+}
+
+func LoadTerrain(path string) (*terrain.Terrain, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := ParseTerrain(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Decode and validate a terrain. Unknown fields are errors, to catch typos
+func ParseTerrain(r io.Reader) (*terrain.Terrain, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var def TerrainDefinition
+	if err := dec.Decode(&def); err != nil {
+		return nil, err
+	}
+	return def.Build()
+}
+
+func (d *TerrainDefinition) Build() (*terrain.Terrain, error) {
+	if d.Size[0] <= 0 || d.Size[1] <= 0 {
+		return nil, defErr("size", "must be positive, got %v", d.Size)
+	}
+	cell := d.Cell
+	if cell == 0 {
+		cell = 40
+	}
+	if cell < 0 {
+		return nil, defErr("cell", "must be positive, got %v", d.Cell)
+	}
+	t := terrain.TerrainNew(ik.Point{}, ik.Point{X: d.Size[0], Y: d.Size[1]}, cell)
+	var err error
+	if t.Obstacles, err = d.Obstacles.build("obstacles"); err != nil {
+		return nil, err
+	}
+	if d.Unwalkable != nil {
+		if t.Unwalkable, err = d.Unwalkable.build("unwalkable"); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+func (d *ShapesDefinition) build(field string) ([]terrain.Shape, error) {
+	shapes := []terrain.Shape{}
+	for i, c := range d.Circles {
+		if c.Radius <= 0 {
+			return nil, defErr(fmt.Sprintf("%s.circles[%d].radius", field, i), "must be positive, got %v", c.Radius)
+		}
+		shapes = append(shapes, terrain.Circle{Center: point(c.Center), Radius: c.Radius})
+	}
+	for i, corners := range d.Polygons {
+		if len(corners) < 3 {
+			return nil, defErr(fmt.Sprintf("%s.polygons[%d]", field, i), "needs at least 3 corners, got %d", len(corners))
+		}
+		poly := terrain.Polygon{}
+		for _, c := range corners {
+			poly.Points = append(poly.Points, point(c))
+		}
+		shapes = append(shapes, poly)
+	}
+	for i, w := range d.Walls {
+		if w.Width <= 0 {
+			return nil, defErr(fmt.Sprintf("%s.walls[%d].width", field, i), "must be positive, got %v", w.Width)
+		}
+		shapes = append(shapes, terrain.Wall{From: point(w.From), To: point(w.To), Width: w.Width})
+	}
+	return shapes, nil
+}
+
+func point(p [2]float64) ik.Point {
+	return ik.Point{X: p[0], Y: p[1]}
+}
+
+// Push every joint of the chain out of the obstacles, (by its radius), and pull the chain back together
+func keepOut(t *terrain.Terrain, chain *ik.Chain) {
+	for i, j := range chain.Joints {
+		j.Pos = t.PushOut(j.Pos, j.Radius)
+		if i > 0 {
+			prev := chain.Joints[i-1]
+			j.Pos = ik.SetConstraint(j.Pos, prev.Pos, prev.Distance)
+			j.Angle = prev.Pos.Angle(j.Pos)
+		}
+	}
+}
+
+// Head for the goal the way around the obstacles: the next waypoint of a path, searched again when the goal
+// moves more than a cell, or every second. The path is wide enough for the widest part of the body
+func PathfindingPath(creature Creature, t *terrain.Terrain, goal TargetPath) TargetPath {
+	body := creature.chains()[0]
+	clearance := 0.0
+	for _, j := range body.Joints {
+		clearance = math.Max(clearance, j.Radius)
+	}
+	var path []ik.Point
+	var planned ik.Point
+	var plannedAt int
+	return func(tick int) ik.Point {
+		head := body.First()
+		g := goal(tick)
+		if path == nil || ik.Distance(g, planned) > t.Cell || tick-plannedAt >= replanTicks {
+			var ok bool
+			if path, ok = t.FindPath(head.Pos, g, clearance); !ok {
+				path = []ik.Point{g}
+			}
+			planned, plannedAt = g, tick
+		}
+		// Skip the waypoints the head has got to
+		for len(path) > 1 && ik.Distance(head.Pos, path[0]) < head.Distance {
+			path = path[1:]
+		}
+		return path[0]
+	}
+}
+
+var (
+	obstacleStyle   = Style{Fill: color.RGBA{90, 96, 104, 255}, Outline: color.RGBA{120, 128, 138, 255}, OutlineWidth: 3}
+	unwalkableStyle = Style{Fill: color.RGBA{36, 62, 84, 255}, Outline: color.RGBA{36, 62, 84, 255}, OutlineWidth: 1}
+)
+
+// Draw the unwalkable regions, and the obstacles on top
+func drawTerrain(screen *ebiten.Image, t *terrain.Terrain) {
+	var vertices []ebiten.Vertex
+	var indices []uint16
+	for _, s := range t.Unwalkable {
+		vertices, indices = drawPath(screen, shapePath(s), unwalkableStyle, vertices, indices)
+	}
+	for _, s := range t.Obstacles {
+		vertices, indices = drawPath(screen, shapePath(s), obstacleStyle, vertices, indices)
+	}
+}
+
+func shapePath(s terrain.Shape) *vector.Path {
+	path := &vector.Path{}
+	switch s := s.(type) {
+	case terrain.Circle:
+		appendEllipse(path, s.Center, s.Radius, s.Radius, 0)
+	case terrain.Polygon:
+		for i, p := range s.Points {
+			if i == 0 {
+				path.MoveTo(float32(p.X), float32(p.Y))
+			} else {
+				path.LineTo(float32(p.X), float32(p.Y))
+			}
+		}
+		path.Close()
+	case terrain.Wall:
+		// Two half circles, joined by the sides
+		r := s.Width / 2
+		a := s.From.Angle(s.To)
+		path.Arc(float32(s.From.X), float32(s.From.Y), float32(r), float32(a+math.Pi/2), float32(a+3*math.Pi/2), vector.Clockwise)
+		path.Arc(float32(s.To.X), float32(s.To.Y), float32(r), float32(a-math.Pi/2), float32(a+math.Pi/2), vector.Clockwise)
+		path.Close()
+	}
+	return path
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestLizardFindsWayAroundWall(t *testing.T) {
+	ground, err := ParseTerrain(strings.NewReader(`{
+		"size": [2400, 1600],
+		"obstacles": {"walls": [{"from": [1200, 0], "to": [1200, 1100], "width": 40}]},
+		"unwalkable": {"circles": [{"center": [1200, 1300], "radius": 120}]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lizard := LizardNew(600, 400)
+	lizard.SetTerrain(ground)
+	goal := ik.Point{X: 1800, Y: 400}
+	sim := SimulatorNew(lizard, PathfindingPath(lizard, ground, WaypointPath([]ik.Point{goal}, 1)))
+
+	deepest, offGround := 0.0, 0
+	for i := 0; i < 30*TickRate; i++ {
+		sim.Step()
+		for _, j := range lizard.chain.Joints {
+			deepest = max(deepest, -ground.Clearance(j.Pos))
+		}
+		for _, l := range lizard.limbs {
+			if l.step.Planted() && !ground.Walkable(l.footPos) {
+				offGround++
+			}
+		}
+	}
+	if d := ik.Distance(lizard.chain.First().Pos, goal); d > 150 {
+		t.Errorf("ERR: actual: %v,  expected: at most %v", d, 150)
+	}
+	if deepest > 0 {
+		t.Errorf("ERR: actual: %v,  expected: no joint in the wall", deepest)
+	}
+	if offGround > 0 {
+		t.Errorf("ERR: actual: %v,  expected: no feet off the ground", offGround)
+	}
+}
+
+func TestTerrainErrors(t *testing.T) {
+	tt := []struct {
+		json  string
+		field string
+	}{
+		{`{"size": [0, 100]}`, "size"},
+		{`{"size": [100, 100], "obstacles": {"circles": [{"center": [1, 1], "radius": 0}]}}`, "obstacles.circles[0].radius"},
+		{`{"size": [100, 100], "unwalkable": {"polygons": [[[0, 0], [1, 1]]]}}`, "unwalkable.polygons[0]"},
+		{`{"size": [100, 100], "obstacles": {"walls": [{"from": [0, 0], "to": [1, 1]}]}}`, "obstacles.walls[0].width"},
+	}
+	for _, tc := range tt {
+		_, err := ParseTerrain(strings.NewReader(tc.json))
+		defErr, ok := err.(*DefinitionError)
+		if !ok || defErr.Field != tc.field {
+			t.Errorf("ERR: actual: %v,  expected: an error in %v", err, tc.field)
+		}
+	}
+}
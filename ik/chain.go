@@ -0,0 +1,270 @@
+package ik
+
+import (
+	"image/color"
+	"math"
+)
+
+// Share of the heading error DIRECT corrects per second
+const DirectTurnRate = 0.6
+
+type Chain struct {
+	Joints []*Joint
+	// Direction the first joint's bend limits are measured from, (e.g. the body the limb is attached to)
+	BaseAngle float64
+	distance  float64
+	x, y      float64
+	// The Verlet mode, when set, and the joint positions of the last step
+	verlet *VerletOptions
+	prev   []Point
+}
+
+func lerp(t, lo, hi float64) float64 {
+	return float64((1-t)*lo + t*hi)
+}
+
+// Loop to create segments. Head first, tail last
+func ChainNew(bodyShape []int, x, y, distance int) *Chain {
+	nbrSegments := len(bodyShape)
+	segments := make([]*Joint, nbrSegments)
+
+	// Create all segments, incl head and tail
+	for i := 0; i < nbrSegments; i++ {
+
+		//color := HSVtoRGBA(HSVtoRGBNorm)(hue, 1, 1)
+		segments[i] = JointNew(
+			float64(x-i*distance), float64(y),
+			float64(distance), float64(bodyShape[i]),
+			color.RGBA{255, 255, 255, 255},
+		)
+	}
+	return &Chain{Joints: segments, distance: float64(distance), x: float64(x), y: float64(y)}
+}
+
+func (c *Chain) First() *Joint {
+	return c.Joints[0]
+}
+
+func (c *Chain) Last() *Joint {
+	return c.Joints[len(c.Joints)-1]
+}
+
+// The distance between the joints, as given to ChainNew
+func (c *Chain) Distance() float64 {
+	return c.distance
+}
+
+func (c *Chain) AdjustedPosX(i int, angleOffset, lengthOffset float64) float64 {
+	s := c.Joints[i]
+	return s.Pos.X + math.Cos(s.Angle+angleOffset)*(s.Radius+lengthOffset)
+}
+func (c *Chain) AdjustedPosY(i int, angleOffset, lengthOffset float64) float64 {
+	s := c.Joints[i]
+	return s.Pos.Y + math.Sin(s.Angle+angleOffset)*(s.Radius+lengthOffset)
+}
+func (c *Chain) AdjustedPos(i int, angleOffset, lengthOffset float64) Point {
+	s := c.Joints[i]
+	return s.AdjustedPos(angleOffset, lengthOffset)
+}
+
+// The direction of the segment leading into joint i
+func (c *Chain) parentDir(i int) float64 {
+	if i == 0 {
+		return c.BaseAngle
+	}
+	return angle(c.Joints[i].Pos.Sub(c.Joints[i-1].Pos))
+}
+
+// Keeps the bend at joint i within its limits, by moving joint i+1
+func (c *Chain) limit(i int) {
+	curr := c.Joints[i]
+	next := c.Joints[i+1]
+	next.Pos = curr.limitChild(c.parentDir(i), next.Pos)
+}
+
+func (c *Chain) SetAnchorPos(p Point) {
+	c.Joints[0].Pos = p
+}
+func (c *Chain) EasyFollow(target Point) {
+	// The first joint follow the target (set position and angle)
+	head := c.First()
+
+	head.Pos = target
+	// update the other segments
+	for i := 1; i < len(c.Joints); i++ {
+		prev := c.Joints[i-1]
+		curr := c.Joints[i]
+		curr.DirectlyFollow(prev.Pos)
+		if i > 1 {
+			c.limit(i - 1)
+			curr.Angle = prev.Pos.Angle(curr.Pos)
+		}
+	}
+}
+
+// Update all segments of the body: the head turns towards the target, and moves speed pixels per second.
+// The rest of the joints follow directly. dt is in seconds
+func (c *Chain) DIRECT(target Point, speed, dt float64) {
+	// The first joint follow the target (set position and angle)
+	head := c.First()
+	targetAngle := math.Atan2(target.Y-head.Pos.Y, target.X-head.Pos.X)
+	delta := targetAngle - head.Angle
+	for delta < -math.Pi {
+		delta += 2 * math.Pi
+	}
+	for delta > math.Pi {
+		delta -= 2 * math.Pi
+	}
+	head.Angle += delta * math.Min(1, DirectTurnRate*dt)
+
+	// Testing another method
+	// delta := targetAngle - nextAngle
+	// if delta < -math.Pi*0.1 {
+	// 	targetAngle = -math.Pi * 0.1
+	// }
+	// if delta > math.Pi*0.1 {
+	// 	targetAngle = math.Pi * 0.1
+	// }
+	// head.Angle += targetAngle * 0.1
+
+	// Update position
+	dist := math.Sqrt(math.Pow(target.X-head.Pos.X, 2) + math.Pow(target.Y-head.Pos.Y, 2))
+	if dist > head.Distance {
+		head.Pos.X += math.Cos(head.Angle) * speed * dt
+		head.Pos.Y += math.Sin(head.Angle) * speed * dt
+	}
+
+	// update the other segments
+	if c.verlet != nil {
+		c.Verlet(dt, *c.verlet)
+		return
+	}
+	for i := 1; i < len(c.Joints); i++ {
+		prev := c.Joints[i-1]
+		curr := c.Joints[i]
+		curr.DirectlyFollow(prev.Pos)
+		if i > 1 {
+			c.limit(i - 1)
+			curr.Angle = prev.Pos.Angle(curr.Pos)
+		}
+	}
+}
+
+// One sweep of Cyclic Coordinate Descent Inverse Kinematics, from the last joint to the anchor.
+// Each joint turns the rest of the chain towards the target, weighted by its Adjustment
+func (c *Chain) CCDIK(target, anchor Point, damping float64) {
+	c.moveTo(anchor)
+	last := c.Last()
+	for i := len(c.Joints) - 2; i >= 0; i-- {
+		curr := c.Joints[i]
+		turn := curr.ccdTurn(last.Pos, target, damping)
+		if curr.Limited {
+			parentDir := c.parentDir(i)
+			bend := wrapAngle(angle(c.Joints[i+1].Pos.Sub(curr.Pos)) - parentDir)
+			turn = curr.clampBend(bend+turn) - bend
+		}
+		for _, j := range c.Joints[i+1:] {
+			j.Pos = j.Pos.Rotate(curr.Pos, turn)
+		}
+	}
+	c.updateAngles()
+}
+
+// Iterates CCDIK until the end effector is within the tolerance of the target, or the iteration cap is hit
+func (c *Chain) SolveCCDIK(target, anchor Point, opts SolverOptions) SolveResult {
+	damping := opts.Damping
+	if damping <= 0 {
+		damping = 1
+	}
+	c.moveTo(anchor)
+	result := SolveResult{
+		Reachable: Distance(anchor, target) <= c.Reach(),
+		Error:     Distance(c.Last().Pos, target),
+	}
+	for result.Iterations < opts.MaxIterations && (result.Iterations == 0 || result.Error > opts.Tolerance) {
+		c.CCDIK(target, anchor, damping)
+		result.Iterations++
+		result.Error = Distance(c.Last().Pos, target)
+	}
+	return result
+}
+
+// Translate the whole chain so the first joint is at p
+func (c *Chain) moveTo(p Point) {
+	delta := p.Sub(c.First().Pos)
+	for _, j := range c.Joints {
+		j.Pos = j.Pos.Add(delta)
+	}
+}
+
+// Point each joint's angle towards the previous joint, (the first one shares the angle of the second)
+func (c *Chain) updateAngles() {
+	for i := 1; i < len(c.Joints); i++ {
+		c.Joints[i].Angle = c.Joints[i-1].Pos.Angle(c.Joints[i].Pos)
+	}
+	if len(c.Joints) > 1 {
+		c.Joints[0].Angle = c.Joints[1].Angle
+	}
+}
+
+// One backward and one forward pass of Forward And Backward Reaching Inverse Kinematics
+func (c *Chain) FABRIK(target, anchor Point) {
+	// Backward loop: Iterate from the last segment to the first, and update each.
+	c.Joints[len(c.Joints)-1].Pos = target
+	for i := len(c.Joints) - 2; i >= 0; i-- {
+		curr := c.Joints[i]
+		next := c.Joints[i+1]
+		curr.Pos = SetConstraint(curr.Pos, next.Pos, curr.Distance)
+	}
+
+	// Forward loop: Update all the following segments start positions, based on the previous segments
+	c.Joints[0].Pos = anchor
+	for i := 1; i < len(c.Joints); i++ {
+		curr := c.Joints[i]
+		prev := c.Joints[i-1]
+		curr.Pos = SetConstraint(curr.Pos, prev.Pos, prev.Distance)
+		c.limit(i - 1)
+		curr.Angle = prev.Pos.Angle(curr.Pos)
+	}
+	c.Joints[0].Angle = c.Joints[0].Pos.Angle(c.Joints[1].Pos)
+
+}
+
+// The length of the chain when fully stretched
+func (c *Chain) Reach() float64 {
+	reach := 0.0
+	for _, j := range c.Joints[:len(c.Joints)-1] {
+		reach += j.Distance
+	}
+	return reach
+}
+
+// Iterates FABRIK until the end effector is within the tolerance of the target, or the iteration cap is hit.
+// An unreachable target straightens the chain towards it instead.
+func (c *Chain) SolveFABRIK(target, anchor Point, opts SolverOptions) SolveResult {
+	if Distance(anchor, target) > c.Reach() {
+		c.straighten(target, anchor)
+		return SolveResult{Iterations: 1, Error: Distance(c.Last().Pos, target), Reachable: false}
+	}
+
+	result := SolveResult{Reachable: true, Error: Distance(c.Last().Pos, target)}
+	for result.Iterations < opts.MaxIterations && (result.Iterations == 0 || result.Error > opts.Tolerance) {
+		c.FABRIK(target, anchor)
+		result.Iterations++
+		result.Error = Distance(c.Last().Pos, target)
+	}
+	return result
+}
+
+// Lay out all joints on the line from the anchor towards the target
+func (c *Chain) straighten(target, anchor Point) {
+	dir := target.Sub(anchor)
+	c.Joints[0].Pos = anchor
+	for i := 1; i < len(c.Joints); i++ {
+		prev := c.Joints[i-1]
+		c.Joints[i].Pos = prev.Pos.Add(dir.SetMag(prev.Distance))
+		c.limit(i - 1)
+		c.Joints[i].Angle = prev.Pos.Angle(c.Joints[i].Pos)
+	}
+	c.Joints[0].Angle = c.Joints[0].Pos.Angle(c.Joints[1].Pos)
+}
@@ -0,0 +1,139 @@
+package ik
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSolveFABRIKReachable(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10}, 0, 0, 40)
+	anchor := Point{0, 0}
+	target := Point{50, 60}
+	opts := SolverOptions{MaxIterations: 50, Tolerance: 0.01}
+
+	res := c.SolveFABRIK(target, anchor, opts)
+	if !res.Reachable || !res.Converged(opts) {
+		t.Errorf("ERR: actual: %+v,  expected: converged", res)
+	}
+	if c.First().Pos != anchor {
+		t.Errorf("ERR: actual: %v,  expected: %v", c.First().Pos, anchor)
+	}
+	for i := 1; i < len(c.Joints); i++ {
+		d := Distance(c.Joints[i-1].Pos, c.Joints[i].Pos)
+		if math.Abs(d-40) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, 40)
+		}
+	}
+}
+
+func TestSolveFABRIKUnreachable(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10}, 0, 0, 40)
+	anchor := Point{0, 0}
+	target := Point{0, 200}
+
+	res := c.SolveFABRIK(target, anchor, DefaultSolverOptions)
+	if res.Reachable {
+		t.Errorf("ERR: actual: %v,  expected: %v", res.Reachable, false)
+	}
+	expected := Point{0, 80}
+	actual := c.Last().Pos
+	if math.Abs(actual.X-expected.X) > 1e-9 || math.Abs(actual.Y-expected.Y) > 1e-9 {
+		t.Errorf("ERR: actual: %v,  expected: %v", actual, expected)
+	}
+	if math.Abs(res.Error-120) > 1e-9 {
+		t.Errorf("ERR: actual: %v,  expected: %v", res.Error, 120)
+	}
+}
+
+func TestFABRIKAngleLimits(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10}, 0, 0, 40)
+	c.Joints[1].SetAngleLimits(0, math.Pi/2)
+	anchor := Point{0, 0}
+
+	// The target is easiest reached by bending the other way, (clockwise)
+	for _, target := range []Point{{40, -30}, {50, 10}, {-20, -20}} {
+		c.SolveFABRIK(target, anchor, DefaultSolverOptions)
+		j := c.Joints
+		bend := wrapAngle(angle(j[2].Pos.Sub(j[1].Pos)) - angle(j[1].Pos.Sub(j[0].Pos)))
+		if bend < -1e-9 || bend > math.Pi/2+1e-9 {
+			t.Errorf("ERR: target %v: actual: %v,  expected: within [0, Pi/2]", target, bend)
+		}
+	}
+}
+
+func TestEasyFollowAngleLimits(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10, 10}, 0, 0, 20)
+	for _, j := range c.Joints[1:] {
+		j.SetAngleLimits(-math.Pi/6, math.Pi/6)
+	}
+	// Turn around on the spot, which would fold the chain
+	for i := 0; i < 200; i++ {
+		c.EasyFollow(Point{float64(-i), float64(i % 7)})
+	}
+	j := c.Joints
+	for i := 1; i < len(j)-1; i++ {
+		bend := wrapAngle(angle(j[i+1].Pos.Sub(j[i].Pos)) - angle(j[i].Pos.Sub(j[i-1].Pos)))
+		if math.Abs(bend) > math.Pi/6+1e-9 {
+			t.Errorf("ERR: joint %d: actual: %v,  expected: within +-Pi/6", i, bend)
+		}
+	}
+}
+
+func TestDIRECTAngleLimits(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10, 10}, 0, 0, 20)
+	for _, j := range c.Joints[1:] {
+		j.SetAngleLimits(-math.Pi/6, math.Pi/6)
+	}
+	// Turn around towards a target behind, in big steps, which would fold the chain
+	for step := 0; step < 100; step++ {
+		c.DIRECT(Point{-200, 10}, 30, 0.5)
+		j := c.Joints
+		for i := 1; i < len(j)-1; i++ {
+			bend := wrapAngle(angle(j[i+1].Pos.Sub(j[i].Pos)) - angle(j[i].Pos.Sub(j[i-1].Pos)))
+			if math.Abs(bend) > math.Pi/6+1e-9 {
+				t.Fatalf("ERR: step %d, joint %d: actual: %v,  expected: within +-Pi/6", step, i, bend)
+			}
+		}
+	}
+	// It did turn around
+	if head := c.First().Pos; head.X > -100 {
+		t.Errorf("ERR: actual: %v,  expected: on the way to %v", head, Point{-200, 10})
+	}
+}
+
+func TestSolveCCDIK(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10}, 0, 0, 40)
+	c.Joints[1].Adjustment = 0.5
+	anchor := Point{100, 100}
+	target := Point{150, 160}
+	opts := SolverOptions{MaxIterations: 100, Tolerance: 0.01, Damping: 0.5}
+
+	res := c.Solve(SolverCCD, target, anchor, opts)
+	if !res.Reachable || !res.Converged(opts) {
+		t.Errorf("ERR: actual: %+v,  expected: converged", res)
+	}
+	if c.First().Pos != anchor {
+		t.Errorf("ERR: actual: %v,  expected: %v", c.First().Pos, anchor)
+	}
+	for i := 1; i < len(c.Joints); i++ {
+		d := Distance(c.Joints[i-1].Pos, c.Joints[i].Pos)
+		if math.Abs(d-40) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, 40)
+		}
+	}
+}
+
+func TestSolveCCDIKAngleLimits(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10}, 0, 0, 40)
+	c.Joints[1].SetAngleLimits(0, math.Pi/2)
+	anchor := Point{0, 0}
+
+	for _, target := range []Point{{40, -30}, {50, 10}, {-20, -20}} {
+		c.SolveCCDIK(target, anchor, DefaultSolverOptions)
+		j := c.Joints
+		bend := wrapAngle(angle(j[2].Pos.Sub(j[1].Pos)) - angle(j[1].Pos.Sub(j[0].Pos)))
+		if bend < -1e-9 || bend > math.Pi/2+1e-9 {
+			t.Errorf("ERR: target %v: actual: %v,  expected: within [0, Pi/2]", target, bend)
+		}
+	}
+}
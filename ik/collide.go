@@ -0,0 +1,147 @@
+package ik
+
+import (
+	"math"
+	"sort"
+)
+
+type CollisionOptions struct {
+	Iterations int     // Passes of pushing apart and reconnecting
+	Scale      float64 // Of Joint.Radius, for the collision circle
+}
+
+var DefaultCollisionOptions = CollisionOptions{Iterations: 2, Scale: 1}
+
+type collider struct {
+	chain, index int
+	joint        *Joint
+	r            float64
+}
+
+// Push apart overlapping joints, each one a circle of its radius: joints of the same chain that are far enough
+// apart along it to not touch when it's straight, and joints of different chains. The heads lead, so they are
+// only pushed by other heads. Then the chains that were pushed are pulled back together, from the head, and
+// within their bend limits. Returns the number of overlaps found, (in the first pass)
+func Collide(chains []*Chain, opts CollisionOptions) int {
+	// Where each joint is along its chain, to tell which joints of the same chain may touch anyway
+	along := make([][]float64, len(chains))
+	for c, chain := range chains {
+		along[c] = make([]float64, len(chain.Joints))
+		for i := 1; i < len(chain.Joints); i++ {
+			along[c][i] = along[c][i-1] + chain.Joints[i-1].Distance
+		}
+	}
+
+	contacts := 0
+	colliders := []collider{}
+	pushed := make([]bool, len(chains))
+	for it := 0; it < max(1, opts.Iterations); it++ {
+		// Broad phase: sort by the left edge, and only test circles whose x ranges overlap
+		colliders = colliders[:0]
+		for c, chain := range chains {
+			for i, j := range chain.Joints {
+				colliders = append(colliders, collider{c, i, j, j.Radius * opts.Scale})
+			}
+		}
+		sort.Slice(colliders, func(a, b int) bool {
+			return colliders[a].joint.Pos.X-colliders[a].r < colliders[b].joint.Pos.X-colliders[b].r
+		})
+		found := 0
+		for a := range colliders {
+			ca := &colliders[a]
+			right := ca.joint.Pos.X + ca.r
+			for b := a + 1; b < len(colliders) && colliders[b].joint.Pos.X-colliders[b].r <= right; b++ {
+				cb := &colliders[b]
+				if ca.chain == cb.chain && math.Abs(along[ca.chain][ca.index]-along[cb.chain][cb.index]) < ca.r+cb.r {
+					continue
+				}
+				if separate(ca, cb, pushed) {
+					found++
+				}
+			}
+		}
+		if it == 0 {
+			contacts = found
+		}
+		if found == 0 {
+			break
+		}
+		for c, chain := range chains {
+			if pushed[c] {
+				chain.relax()
+			}
+		}
+	}
+	for c, chain := range chains {
+		if pushed[c] {
+			chain.reconnect()
+		}
+	}
+	return contacts
+}
+
+// Push two overlapping circles apart, (only the heads' circles if both are heads), and mark the chains moved.
+// True if they overlapped
+func separate(a, b *collider, pushed []bool) bool {
+	delta := b.joint.Pos.Sub(a.joint.Pos)
+	d := delta.Mag()
+	overlap := a.r + b.r - d
+	if overlap <= 0 {
+		return false
+	}
+	if d == 0 {
+		delta, d = Point{1, 0}, 1
+	}
+	wa, wb := 1.0, 1.0
+	if a.index == 0 {
+		wa = 0
+	}
+	if b.index == 0 {
+		wb = 0
+	}
+	if wa+wb == 0 {
+		wa, wb = 1, 1
+	}
+	pushed[a.chain] = pushed[a.chain] || wa > 0
+	pushed[b.chain] = pushed[b.chain] || wb > 0
+	n := Point{delta.X / d, delta.Y / d}
+	share := overlap / (wa + wb)
+	a.joint.Pos = a.joint.Pos.Sub(Point{n.X * share * wa, n.Y * share * wa})
+	b.joint.Pos = b.joint.Pos.Add(Point{n.X * share * wb, n.Y * share * wb})
+	return true
+}
+
+// Pull the joints towards their distances, both ends of each segment half way, (but not the head). Bends the
+// chain around what pushed it, rather than passing the push on down the chain
+func (c *Chain) relax() {
+	for i := 1; i < len(c.Joints); i++ {
+		prev := c.Joints[i-1]
+		curr := c.Joints[i]
+		delta := curr.Pos.Sub(prev.Pos)
+		d := delta.Mag()
+		if d == 0 {
+			continue
+		}
+		fix := (d - prev.Distance) / d
+		if i == 1 {
+			curr.Pos = curr.Pos.Sub(Point{delta.X * fix, delta.Y * fix})
+			continue
+		}
+		prev.Pos = prev.Pos.Add(Point{delta.X * fix / 2, delta.Y * fix / 2})
+		curr.Pos = curr.Pos.Sub(Point{delta.X * fix / 2, delta.Y * fix / 2})
+	}
+}
+
+// Pull every joint back to its distance from the one before, from the head, within the bend limits, (past the
+// head, like EasyFollow), and point it that way
+func (c *Chain) reconnect() {
+	for i := 1; i < len(c.Joints); i++ {
+		prev := c.Joints[i-1]
+		curr := c.Joints[i]
+		curr.Pos = SetConstraint(curr.Pos, prev.Pos, prev.Distance)
+		if i > 1 {
+			c.limit(i - 1)
+		}
+		curr.Angle = prev.Pos.Angle(curr.Pos)
+	}
+}
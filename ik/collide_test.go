@@ -0,0 +1,111 @@
+package ik
+
+import (
+	"math"
+	"testing"
+)
+
+// The deepest overlap between joints that Collide should keep apart
+func penetration(chains []*Chain) float64 {
+	deepest := 0.0
+	for c, chain := range chains {
+		for i, a := range chain.Joints {
+			for d := c; d < len(chains); d++ {
+				for k, b := range chains[d].Joints {
+					if d == c && (k <= i || float64(k-i)*chain.distance < a.Radius+b.Radius) {
+						continue
+					}
+					deepest = math.Max(deepest, a.Radius+b.Radius-Distance(a.Pos, b.Pos))
+				}
+			}
+		}
+	}
+	return deepest
+}
+
+func TestCollideSelf(t *testing.T) {
+	// Turn around on the spot, which folds the tail back across the body
+	c := ChainNew([]int{10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10, 10}, 0, 0, 15)
+	for i := 0; i < 30; i++ {
+		c.EasyFollow(Point{float64(-i) * 6, float64(i % 3)})
+	}
+	if p := penetration([]*Chain{c}); p < 5 {
+		t.Fatalf("ERR: actual: %v,  expected: a folded chain", p)
+	}
+	head := c.First().Pos
+	Collide([]*Chain{c}, CollisionOptions{Iterations: 20, Scale: 1})
+	if p := penetration([]*Chain{c}); p > 0.5 {
+		t.Errorf("ERR: actual: %v,  expected: at most %v", p, 0.5)
+	}
+	if c.First().Pos != head {
+		t.Errorf("ERR: actual: %v,  expected: %v", c.First().Pos, head)
+	}
+	for i := 1; i < len(c.Joints); i++ {
+		d := Distance(c.Joints[i-1].Pos, c.Joints[i].Pos)
+		if math.Abs(d-15) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, 15)
+		}
+	}
+}
+
+func TestCollideChains(t *testing.T) {
+	// One chain crawls straight across the other, which is pushed out of the way
+	a := ChainNew([]int{20, 20, 20, 20, 20, 20}, 200, 100, 30)
+	b := ChainNew([]int{15, 15, 15, 15}, 110, 300, 30)
+	for i, j := range b.Joints {
+		j.Pos = Point{110, 300 + float64(i)*30}
+	}
+	deepest := 0.0
+	for i := 0; i < 150; i++ {
+		b.EasyFollow(Point{110, 300 - float64(i)*2})
+		Collide([]*Chain{a, b}, DefaultCollisionOptions)
+		deepest = math.Max(deepest, penetration([]*Chain{a, b}))
+	}
+	// No deeper than the head moves in a tick
+	if deepest > 2.5 {
+		t.Errorf("ERR: actual: %v,  expected: at most %v", deepest, 2.5)
+	}
+	if a.First().Pos != (Point{200, 100}) {
+		t.Errorf("ERR: actual: %v,  expected: %v", a.First().Pos, Point{200, 100})
+	}
+}
+
+// Only the chains that were pushed are reconnected, and within their bend limits
+func TestCollideKeepsLimits(t *testing.T) {
+	// A stiff chain, with its middle pushed sideways by the head of another
+	a := ChainNew([]int{10, 10, 10, 10, 10, 10}, 100, 100, 20)
+	for _, j := range a.Joints[:len(a.Joints)-1] {
+		j.SetAngleLimits(-0.2, 0.2)
+	}
+	b := ChainNew([]int{15, 15, 15}, 60, 88, 20)
+	for i, j := range b.Joints {
+		j.Pos = Point{60, 88 - float64(i)*20}
+	}
+	// And one out of the way, bent further than its limits allow, which is left as it is
+	far := ChainNew([]int{5, 5, 5, 5}, 1000, 1000, 20)
+	far.Joints[2].Pos = Point{1000 - 20, 1020}
+	far.Joints[3].Pos = Point{1000 - 20, 1040}
+	for _, j := range far.Joints[:len(far.Joints)-1] {
+		j.SetAngleLimits(-0.2, 0.2)
+	}
+	before := make([]Joint, len(far.Joints))
+	for i, j := range far.Joints {
+		before[i] = *j
+	}
+
+	if n := Collide([]*Chain{a, b, far}, DefaultCollisionOptions); n == 0 {
+		t.Fatalf("ERR: actual: %v,  expected: overlaps", n)
+	}
+	for i := 1; i < len(a.Joints)-1; i++ {
+		j := a.Joints[i]
+		bend := wrapAngle(angle(a.Joints[i+1].Pos.Sub(j.Pos)) - a.parentDir(i))
+		if bend < j.MinAngle-1e-9 || bend > j.MaxAngle+1e-9 {
+			t.Errorf("ERR: joint %d: actual: %v,  expected: within [%v, %v]", i, bend, j.MinAngle, j.MaxAngle)
+		}
+	}
+	for i, j := range far.Joints {
+		if *j != before[i] {
+			t.Errorf("ERR: joint %d: actual: %v,  expected: %v", i, *j, before[i])
+		}
+	}
+}
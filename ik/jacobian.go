@@ -0,0 +1,220 @@
+package ik
+
+import "math"
+
+// A goal for the Jacobian solvers: a position for the end effector, and optionally a direction for the last segment
+type Goal struct {
+	Pos         Point
+	Angle       float64 // Direction of the last segment, only used when Oriented
+	Oriented    bool
+	AngleWeight float64 // How many pixels of position error one radian of orientation error is worth, (0 means the chain's reach)
+}
+
+// Settings for the Jacobian solvers
+type JacobianOptions struct {
+	SolverOptions
+	Lambda  float64 // Damped least squares: keeps the steps bounded near singular poses
+	MaxStep float64 // Largest change of a joint angle per iteration, (0 means no cap)
+}
+
+var DefaultJacobianOptions = JacobianOptions{
+	SolverOptions: SolverOptions{MaxIterations: 50, Tolerance: 0.5},
+	Lambda:        10,
+	MaxStep:       math.Pi / 8,
+}
+
+// Iterates the Jacobian transpose method: turn each joint along the gradient of the error
+func (c *Chain) SolveJacobianTranspose(goal Goal, anchor Point, opts JacobianOptions) SolveResult {
+	return c.solveJacobian(goal, anchor, opts, false)
+}
+
+// Iterates the damped least squares method, which converges faster than the transpose and
+// stays stable when the chain is stretched out or folded, (where the Jacobian is singular)
+func (c *Chain) SolveDLS(goal Goal, anchor Point, opts JacobianOptions) SolveResult {
+	return c.solveJacobian(goal, anchor, opts, true)
+}
+
+func (c *Chain) solveJacobian(goal Goal, anchor Point, opts JacobianOptions, dls bool) SolveResult {
+	n := len(c.Joints) - 1
+	if n < 1 {
+		c.moveTo(anchor)
+		return SolveResult{Reachable: anchor == goal.Pos, Error: Distance(anchor, goal.Pos)}
+	}
+	weight := goal.AngleWeight
+	if weight <= 0 {
+		weight = c.Reach()
+	}
+
+	// Work on the bends of the joints, (relative angles)
+	c.moveTo(anchor)
+	bends := make([]float64, n)
+	for i := range bends {
+		bends[i] = wrapAngle(angle(c.Joints[i+1].Pos.Sub(c.Joints[i].Pos)) - c.parentDir(i))
+	}
+
+	result := SolveResult{Reachable: Distance(anchor, goal.Pos) <= c.Reach()}
+	rows := 2
+	if goal.Oriented {
+		rows = 3
+	}
+	jac := make([][3]float64, n)
+	for {
+		c.forwardKinematics(bends)
+
+		// The error vector: position, and weighted orientation
+		end := c.Last().Pos
+		e := [3]float64{goal.Pos.X - end.X, goal.Pos.Y - end.Y, 0}
+		result.Error = Distance(end, goal.Pos)
+		result.AngleError = 0
+		if goal.Oriented {
+			turn := wrapAngle(goal.Angle - angle(end.Sub(c.Joints[n-1].Pos)))
+			result.AngleError = math.Abs(turn)
+			e[2] = turn * weight
+		}
+		done := result.Error <= opts.Tolerance && result.AngleError*weight <= opts.Tolerance
+		if done || result.Iterations >= opts.MaxIterations {
+			break
+		}
+
+		// Each column: how the end effector moves when a joint turns
+		for i := 0; i < n; i++ {
+			r := end.Sub(c.Joints[i].Pos)
+			jac[i] = [3]float64{-r.Y, r.X, weight}
+		}
+
+		var step []float64
+		if dls {
+			step = dlsStep(jac, e, rows, opts.Lambda)
+		} else {
+			step = transposeStep(jac, e, rows)
+		}
+		if step == nil || isStuck(step) {
+			// Singular pose, (e.g. stretched straight towards a target in front of it): no joint
+			// turn moves the end effector towards the goal, so nudge the joints out of it
+			step = make([]float64, n)
+			for i := range step {
+				step[i] = singularNudge
+			}
+		}
+		for i := range bends {
+			d := step[i]
+			if opts.MaxStep > 0 {
+				d = math.Max(-opts.MaxStep, math.Min(opts.MaxStep, d))
+			}
+			bends[i] = c.Joints[i].clampBend(wrapAngle(bends[i] + d))
+		}
+		result.Iterations++
+	}
+	c.updateAngles()
+	return result
+}
+
+const singularNudge = 0.05
+
+func isStuck(step []float64) bool {
+	for _, d := range step {
+		if math.Abs(d) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// Place the joints from the first one, with the given bends and the joints' distances
+func (c *Chain) forwardKinematics(bends []float64) {
+	dir := c.BaseAngle
+	for i, bend := range bends {
+		dir += bend
+		curr := c.Joints[i]
+		c.Joints[i+1].Pos = Point{curr.Pos.X + curr.Distance*math.Cos(dir), curr.Pos.Y + curr.Distance*math.Sin(dir)}
+	}
+}
+
+// Jacobian transpose step, scaled to the best length along its direction. Nil if the chain can't move towards the goal
+func transposeStep(jac [][3]float64, e [3]float64, rows int) []float64 {
+	step := make([]float64, len(jac))
+	for i, col := range jac {
+		for r := 0; r < rows; r++ {
+			step[i] += col[r] * e[r]
+		}
+	}
+	// J * step, to find how far to go
+	var jj [3]float64
+	for i, col := range jac {
+		for r := 0; r < rows; r++ {
+			jj[r] += col[r] * step[i]
+		}
+	}
+	num, den := 0.0, 0.0
+	for r := 0; r < rows; r++ {
+		num += e[r] * jj[r]
+		den += jj[r] * jj[r]
+	}
+	if den < 1e-12 {
+		return nil
+	}
+	alpha := num / den
+	for i := range step {
+		step[i] *= alpha
+	}
+	return step
+}
+
+// Damped least squares step: J^T (J J^T + lambda^2 I)^-1 e. Nil if the system can't be solved
+func dlsStep(jac [][3]float64, e [3]float64, rows int, lambda float64) []float64 {
+	var m [3][3]float64
+	for _, col := range jac {
+		for r := 0; r < rows; r++ {
+			for k := 0; k < rows; k++ {
+				m[r][k] += col[r] * col[k]
+			}
+		}
+	}
+	for r := 0; r < rows; r++ {
+		m[r][r] += lambda * lambda
+	}
+	f, ok := solveLinear(m, e, rows)
+	if !ok {
+		return nil
+	}
+	step := make([]float64, len(jac))
+	for i, col := range jac {
+		for r := 0; r < rows; r++ {
+			step[i] += col[r] * f[r]
+		}
+	}
+	return step
+}
+
+// Solve m x = b for the first n rows, with Gaussian elimination and partial pivoting
+func solveLinear(m [3][3]float64, b [3]float64, n int) ([3]float64, bool) {
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return b, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := m[r][col] / m[col][col]
+			for k := col; k < n; k++ {
+				m[r][k] -= f * m[col][k]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	var x [3]float64
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for k := r + 1; k < n; k++ {
+			sum -= m[r][k] * x[k]
+		}
+		x[r] = sum / m[r][r]
+	}
+	return x, true
+}
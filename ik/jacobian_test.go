@@ -0,0 +1,44 @@
+package ik
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSolveDLSOriented(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10}, 0, 0, 40)
+	anchor := Point{0, 0}
+	goal := Goal{Pos: Point{60, 40}, Angle: math.Pi / 2, Oriented: true}
+	opts := DefaultJacobianOptions
+	opts.MaxIterations = 500
+	opts.Tolerance = 0.05
+
+	res := c.SolveDLS(goal, anchor, opts)
+	if res.Error > 0.05 || res.AngleError > 0.01 {
+		t.Errorf("ERR: actual: %+v,  expected: converged", res)
+	}
+	actual := c.Last().Pos.Sub(c.Joints[2].Pos)
+	if math.Abs(angle(actual)-math.Pi/2) > 0.01 {
+		t.Errorf("ERR: actual: %v,  expected: %v", angle(actual), math.Pi/2)
+	}
+	for i := 1; i < len(c.Joints); i++ {
+		d := Distance(c.Joints[i-1].Pos, c.Joints[i].Pos)
+		if math.Abs(d-40) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, 40)
+		}
+	}
+}
+
+func TestSolveJacobianSingular(t *testing.T) {
+	// Stretched straight along the x axis, with the target in front of the chain
+	for _, solver := range []Solver{SolverJacobianTranspose, SolverDLS} {
+		c := ChainNew([]int{10, 10, 10}, 0, 0, 40)
+		c.BaseAngle = math.Pi
+		opts := DefaultSolverOptions
+		opts.MaxIterations = 500
+		res := c.Solve(solver, Point{-50, 0}, Point{0, 0}, opts)
+		if math.IsNaN(res.Error) || !res.Converged(opts) {
+			t.Errorf("ERR: %v: actual: %+v,  expected: converged", solver, res)
+		}
+	}
+}
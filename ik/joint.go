@@ -0,0 +1,127 @@
+package ik
+
+import (
+	"image/color"
+	"math"
+)
+
+type Joint struct {
+	Pos        Point
+	Distance   float64
+	Radius     float64
+	Color      color.RGBA
+	Angle      float64
+	Adjustment float64
+	// Bend limits, relative to the parent segment. Only used when Limited is set
+	Limited  bool
+	MinAngle float64
+	MaxAngle float64
+}
+
+func JointNew(x, y, distance, radius float64, color color.RGBA) *Joint {
+	return &Joint{
+		Distance:   distance,
+		Pos:        Point{x, y},
+		Radius:     radius,
+		Color:      color,
+		Adjustment: 1.0,
+	}
+}
+
+// Rotates the segment towards the segment it follows. And translates it to the distance from the segment it follows
+func (s *Joint) DirectlyFollow(prev Point) {
+	// Turn
+	targetAngle := math.Atan2(prev.Y-s.Pos.Y, prev.X-s.Pos.X)
+	s.Angle = targetAngle
+
+	// Move
+	dist := math.Sqrt(math.Pow(prev.X-s.Pos.X, 2) + math.Pow(prev.Y-s.Pos.Y, 2))
+	if dist > s.Distance {
+		delta := dist - s.Distance
+		s.Pos.X += delta * math.Cos(s.Angle)
+		s.Pos.Y += delta * math.Sin(s.Angle)
+
+	}
+}
+
+// How much to turn the joint, (to make the end effector align with the target position)
+func (s *Joint) ccdTurn(end, target Point, damping float64) float64 {
+	angle := math.Atan2(end.Y-s.Pos.Y, end.X-s.Pos.X)
+	targetAngle := math.Atan2(target.Y-s.Pos.Y, target.X-s.Pos.X)
+	return wrapAngle(targetAngle-angle) * s.Adjustment * damping
+}
+
+// Restrict how much the joint may bend: the angle of its own segment relative to the parent segment
+func (s *Joint) SetAngleLimits(min, max float64) {
+	s.Limited = true
+	s.MinAngle = min
+	s.MaxAngle = max
+}
+
+func (s *Joint) clampBend(bend float64) float64 {
+	if !s.Limited {
+		return bend
+	}
+	return math.Max(s.MinAngle, math.Min(s.MaxAngle, bend))
+}
+
+// Rotates child (the position of the next joint) around the joint, so the bend relative to parentDir is within the limits
+func (s *Joint) limitChild(parentDir float64, child Point) Point {
+	if !s.Limited {
+		return child
+	}
+	bend := wrapAngle(angle(child.Sub(s.Pos)) - parentDir)
+	clamped := s.clampBend(bend)
+	if clamped == bend {
+		return child
+	}
+	d := Distance(s.Pos, child)
+	a := parentDir + clamped
+	return Point{s.Pos.X + d*math.Cos(a), s.Pos.Y + d*math.Sin(a)}
+}
+
+// Rotates parent (the position of the previous joint) around the joint, so the bend towards childDir is within the limits
+func (s *Joint) limitParent(childDir float64, parent Point) Point {
+	if !s.Limited {
+		return parent
+	}
+	bend := wrapAngle(childDir - angle(s.Pos.Sub(parent)))
+	clamped := s.clampBend(bend)
+	if clamped == bend {
+		return parent
+	}
+	d := Distance(s.Pos, parent)
+	a := childDir - clamped
+	return Point{s.Pos.X - d*math.Cos(a), s.Pos.Y - d*math.Sin(a)}
+}
+
+func (s *Joint) Left() Point {
+	angle := s.Angle - math.Pi*0.5
+
+	x := s.Pos.X + s.Radius*math.Cos(angle)
+	y := s.Pos.Y + s.Radius*math.Sin(angle)
+
+	return Point{x, y}
+}
+
+func (s *Joint) Right() Point {
+	angle := s.Angle + math.Pi*0.5
+
+	x := s.Pos.X + s.Radius*math.Cos(angle)
+	y := s.Pos.Y + s.Radius*math.Sin(angle)
+
+	return Point{x, y}
+}
+
+func (s *Joint) End() Point {
+	x1 := s.Pos.X + math.Cos(s.Angle)*s.Distance
+	y1 := s.Pos.Y + math.Sin(s.Angle)*s.Distance
+	return Point{x1, y1}
+}
+
+// Point on the joint's circle, rotated angleOffset from the joint's angle and pushed lengthOffset outside the radius
+func (s *Joint) AdjustedPos(angleOffset, lengthOffset float64) Point {
+	x := s.Pos.X + math.Cos(s.Angle+angleOffset)*(s.Radius+lengthOffset)
+	y := s.Pos.Y + math.Sin(s.Angle+angleOffset)*(s.Radius+lengthOffset)
+	return Point{x, y}
+}
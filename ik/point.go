@@ -0,0 +1,94 @@
+// Package ik contains the inverse kinematics core: points, joints, chains and
+// the solvers that move them. It has no rendering dependencies.
+package ik
+
+import "math"
+
+type Point struct {
+	X, Y float64
+}
+
+func Distance(p, q Point) float64 {
+	return math.Sqrt(math.Pow(p.X-q.X, 2) + math.Pow(p.Y-q.Y, 2))
+}
+func angle(p Point) float64 {
+	return math.Atan2(p.Y, p.X)
+}
+
+// Wrap an angle into [-Pi, Pi]
+func wrapAngle(a float64) float64 {
+	for a < -math.Pi {
+		a += 2 * math.Pi
+	}
+	for a > math.Pi {
+		a -= 2 * math.Pi
+	}
+	return a
+}
+func (p Point) Angle(q Point) float64 {
+	return angle(p.Sub(q))
+}
+func SetConstraint(pos, anchor Point, constraint float64) Point {
+	delta := pos.Sub(anchor)
+	deltaConstrained := delta.SetMag(constraint)
+	return anchor.Add(deltaConstrained)
+}
+func (p Point) Add(q Point) Point {
+	p.X += q.X
+	p.Y += q.Y
+	return p
+}
+
+func (p Point) Sub(q Point) Point {
+	x := p.X - q.X
+	y := p.Y - q.Y
+	return Point{x, y}
+}
+
+func (p Point) Dot(q Point) float64 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+func (p Point) Mag() float64 {
+	return math.Sqrt(math.Pow(p.X, 2) + math.Pow(p.Y, 2))
+}
+
+func (p Point) SetMag(m float64) Point {
+	angle := math.Atan2(p.Y, p.X)
+	x := m * math.Cos(angle)
+	y := m * math.Sin(angle)
+	return Point{x, y}
+}
+
+// Rotate the point around the pivot
+func (p Point) Rotate(pivot Point, angle float64) Point {
+	d := p.Sub(pivot)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	return Point{pivot.X + d.X*cos - d.Y*sin, pivot.Y + d.X*sin + d.Y*cos}
+}
+
+// The closest point to p on the segment from a to b
+func ClosestOnSegment(p, a, b Point) Point {
+	ab := b.Sub(a)
+	l := ab.Dot(ab)
+	if l == 0 {
+		return a
+	}
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/l))
+	return Point{a.X + ab.X*t, a.Y + ab.Y*t}
+}
+
+// A round area, (e.g. an obstacle, or ground a foot can't be put down on)
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// The closest point on the outline, and whether p is inside
+func (c Circle) Closest(p Point) (Point, bool) {
+	d := p.Sub(c.Center)
+	if d.Mag() == 0 {
+		return c.Center.Add(Point{X: c.Radius}), true
+	}
+	return c.Center.Add(d.SetMag(c.Radius)), d.Mag() < c.Radius
+}
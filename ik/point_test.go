@@ -1,4 +1,4 @@
-package main
+package ik
 
 import (
 	"fmt"
@@ -9,9 +9,9 @@ import (
 func TestDistance(t *testing.T) {
 	p := Point{3, 0}
 	q := Point{0, 4}
-	actual := distance(p, q)
+	actual := Distance(p, q)
 	expected := 5.0
-	fmt.Printf("PASS - distance(): actual: %v,  expected: %v\n", actual, expected)
+	fmt.Printf("PASS - Distance(): actual: %v,  expected: %v\n", actual, expected)
 	if actual != expected {
 		t.Errorf("ERR: actual: %v,  expected: %v", actual, expected)
 	}
@@ -54,8 +54,8 @@ func TestSetMag(t *testing.T) {
 	actual := p.SetMag(5)
 	expected := Point{3, 4}
 
-	actual.x = math.Round(actual.x)
-	actual.y = math.Round(actual.y)
+	actual.X = math.Round(actual.X)
+	actual.Y = math.Round(actual.Y)
 	fmt.Printf("PASS - SetMag(): actual: %v,  expected: %v\n", actual, expected)
 	if actual != expected {
 		t.Errorf("ERR: actual: %v,  expected: %v", actual, expected)
@@ -68,8 +68,8 @@ func TestSetConstraint(t *testing.T) {
 	actual := SetConstraint(p, q, 5)
 	expected := Point{4, 5}
 
-	actual.x = math.Round(actual.x)
-	actual.y = math.Round(actual.y)
+	actual.X = math.Round(actual.X)
+	actual.Y = math.Round(actual.Y)
 	fmt.Printf("PASS - SetConstraint(): actual: %v,  expected: %v\n", actual, expected)
 	if actual != expected {
 		t.Errorf("ERR: actual: %v,  expected: %v", actual, expected)
@@ -0,0 +1,301 @@
+package ik
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Invariants of the solvers, on random chains and targets. The same checks back the fuzz targets:
+//
+//	go test ./ik -fuzz FuzzFABRIK
+
+const (
+	propertyCases = 300
+	lengthEpsilon = 1e-6 // Relative to the segment
+)
+
+// A chain of n joints with random segment lengths, bent at random, (or with all joints on top of each other).
+// Limited, every joint with a child may bend only so far, (some way either side of straight), and the first one
+// from a random base angle. The starting bends may be outside the limits: the solvers bring them in
+func randomChain(seed int64, n int, coincident, limited bool) *Chain {
+	r := rand.New(rand.NewSource(seed))
+	c := &Chain{}
+	pos := Point{r.Float64()*400 - 200, r.Float64()*400 - 200}
+	dir := r.Float64() * 2 * math.Pi
+	for i := 0; i < n; i++ {
+		j := JointNew(pos.X, pos.Y, 5+r.Float64()*45, 10, color.RGBA{255, 255, 255, 255})
+		c.Joints = append(c.Joints, j)
+		dir += (r.Float64() - 0.5) * math.Pi
+		if !coincident {
+			pos = pos.Add(Point{math.Cos(dir) * j.Distance, math.Sin(dir) * j.Distance})
+		}
+	}
+	c.updateAngles()
+	if limited {
+		c.BaseAngle = r.Float64() * 2 * math.Pi
+		for _, j := range c.Joints[:n-1] {
+			j.SetAngleLimits(-0.1-r.Float64()*1.5, 0.1+r.Float64()*1.5)
+		}
+	}
+	return c
+}
+
+// The fuzzer's numbers, as a chain and a target within the plane the creatures live in. False for input to skip
+func fuzzCase(seed int64, joints uint8, coincident, limited bool, dx, dy float64) (*Chain, Point, bool) {
+	if math.IsNaN(dx) || math.IsNaN(dy) || math.Abs(dx) > 1e4 || math.Abs(dy) > 1e4 {
+		return nil, Point{}, false
+	}
+	c := randomChain(seed, 2+int(joints)%15, coincident, limited)
+	return c, c.First().Pos.Add(Point{dx, dy}), true
+}
+
+// The nearest and furthest the end can be from the anchor
+func reachRange(c *Chain) (float64, float64) {
+	reach, longest := c.Reach(), 0.0
+	for _, j := range c.Joints[:len(c.Joints)-1] {
+		longest = math.Max(longest, j.Distance)
+	}
+	return math.Max(0, 2*longest-reach), reach
+}
+
+// Clearly reachable, or clearly not, (targets close to the edge of the range converge too slowly to tell)
+func reachable(c *Chain, anchor, target Point) (yes, no bool) {
+	inner, outer := reachRange(c)
+	d := Distance(anchor, target)
+	margin := 0.05 * outer
+	return d > inner+margin && d < outer-margin, d > outer+margin
+}
+
+func segments(c *Chain) []float64 {
+	lengths := make([]float64, len(c.Joints)-1)
+	for i := range lengths {
+		lengths[i] = Distance(c.Joints[i].Pos, c.Joints[i+1].Pos)
+	}
+	return lengths
+}
+
+func checkNoNaN(t *testing.T, c *Chain) {
+	t.Helper()
+	for i, j := range c.Joints {
+		if math.IsNaN(j.Pos.X) || math.IsNaN(j.Pos.Y) || math.IsNaN(j.Angle) {
+			t.Fatalf("ERR: joint %d: actual: %v %v,  expected: no NaN", i, j.Pos, j.Angle)
+		}
+	}
+}
+
+// Each segment as long as expected, or no longer, (for the followers, which only pull the joint behind)
+func checkLengths(t *testing.T, c *Chain, expected []float64, orShorter bool) {
+	t.Helper()
+	for i, d := range segments(c) {
+		eps := lengthEpsilon * math.Max(1, expected[i])
+		if d > expected[i]+eps || (!orShorter && d < expected[i]-eps) {
+			t.Fatalf("ERR: segment %d: actual: %v,  expected: %v", i, d, expected[i])
+		}
+	}
+}
+
+// Each limited joint, from the first one on, bent within its limits
+func checkBends(t *testing.T, c *Chain, first int) {
+	t.Helper()
+	for i := first; i < len(c.Joints)-1; i++ {
+		j := c.Joints[i]
+		if !j.Limited || Distance(j.Pos, c.Joints[i+1].Pos) == 0 || (i > 0 && Distance(c.Joints[i-1].Pos, j.Pos) == 0) {
+			continue // (no direction to bend from or to)
+		}
+		bend := wrapAngle(angle(c.Joints[i+1].Pos.Sub(j.Pos)) - c.parentDir(i))
+		if bend < j.MinAngle-1e-6 || bend > j.MaxAngle+1e-6 {
+			t.Fatalf("ERR: joint %d: actual: %v,  expected: within [%v, %v]", i, bend, j.MinAngle, j.MaxAngle)
+		}
+	}
+}
+
+func limited(c *Chain) bool {
+	return c.First().Limited
+}
+
+func checkAnchor(t *testing.T, c *Chain, anchor Point) {
+	t.Helper()
+	if Distance(c.First().Pos, anchor) > 1e-9 {
+		t.Fatalf("ERR: actual: %v,  expected: %v", c.First().Pos, anchor)
+	}
+}
+
+// Every joint on the line from the anchor towards the target, fully stretched
+func checkStraight(t *testing.T, c *Chain, anchor, target Point, tolerance float64) {
+	t.Helper()
+	dir := target.Sub(anchor)
+	dir = Point{dir.X / dir.Mag(), dir.Y / dir.Mag()}
+	along := 0.0
+	for i, j := range c.Joints {
+		if Distance(j.Pos, anchor.Add(Point{dir.X * along, dir.Y * along})) > tolerance {
+			t.Fatalf("ERR: joint %d: actual: %v,  expected: %v along %v from %v", i, j.Pos, along, dir, anchor)
+		}
+		along += j.Distance
+	}
+}
+
+// A chain with every joint on the same spot is laid out on a line, (the SetMag fallback), and FABRIK can't bend
+// it off the line again. So it only keeps the invariants
+func checkFABRIK(t *testing.T, c *Chain, target Point) {
+	anchor := c.First().Pos
+	coincident := Distance(c.Joints[0].Pos, c.Joints[1].Pos) == 0
+	expected := make([]float64, len(c.Joints)-1)
+	for i := range expected {
+		expected[i] = c.Joints[i].Distance
+	}
+	yes, no := reachable(c, anchor, target)
+	opts := SolverOptions{MaxIterations: 10000, Tolerance: 1e-3} // (near folded poses take a while)
+	res := c.SolveFABRIK(target, anchor, opts)
+
+	checkNoNaN(t, c)
+	checkAnchor(t, c, anchor)
+	checkLengths(t, c, expected, false)
+	checkBends(t, c, 0)
+	if coincident || limited(c) {
+		return // (limits may keep the target out of reach)
+	}
+	if yes && !res.Converged(opts) {
+		t.Errorf("ERR: actual: %+v,  expected: converged on %v from %v", res, target, anchor)
+	}
+	if no {
+		checkStraight(t, c, anchor, target, 1e-6*c.Reach())
+	}
+}
+
+// CCD only turns the segments, so they keep whatever lengths they had. It straightens out towards an unreachable
+// target only in the limit, so it gets more sweeps and a looser tolerance
+func checkCCD(t *testing.T, c *Chain, target Point) {
+	anchor := c.First().Pos
+	expected := segments(c)
+	yes, no := reachable(c, anchor, target)
+	opts := SolverOptions{MaxIterations: 10000, Tolerance: 1e-2, Damping: 1}
+	if no {
+		opts.MaxIterations = 5000
+	}
+	res := c.SolveCCDIK(target, anchor, opts)
+
+	checkNoNaN(t, c)
+	checkAnchor(t, c, anchor)
+	checkLengths(t, c, expected, false)
+	checkBends(t, c, 0)
+	if expected[0] == 0 || limited(c) {
+		return // (coincident joints can't reach anything, and limits may keep the target out of reach)
+	}
+	if yes && !res.Converged(opts) {
+		t.Errorf("ERR: actual: %+v,  expected: converged on %v from %v", res, target, anchor)
+	}
+	if no {
+		checkStraight(t, c, anchor, target, 1e-2*c.Reach())
+	}
+}
+
+// The head goes straight to the target, and the rest follow it without stretching, (and within the limits, past
+// the head, which is led rather than bent)
+func checkEasyFollow(t *testing.T, c *Chain, target Point) {
+	expected := make([]float64, len(c.Joints)-1)
+	for i := range expected {
+		expected[i] = math.Max(c.Joints[i+1].Distance, Distance(c.Joints[i].Pos, c.Joints[i+1].Pos))
+	}
+	c.EasyFollow(target)
+
+	checkNoNaN(t, c)
+	if c.First().Pos != target {
+		t.Errorf("ERR: actual: %v,  expected: %v", c.First().Pos, target)
+	}
+	checkLengths(t, c, expected, true)
+	checkBends(t, c, 1)
+}
+
+// The head moves at most speed * dt a step, and the rest follow it like EasyFollow
+func checkDIRECT(t *testing.T, c *Chain, target Point) {
+	const speed, dt = 200.0, 1.0 / 60
+	expected := make([]float64, len(c.Joints)-1)
+	for i := range expected {
+		expected[i] = math.Max(c.Joints[i+1].Distance, Distance(c.Joints[i].Pos, c.Joints[i+1].Pos))
+	}
+	for i := 0; i < 120; i++ {
+		head := c.First().Pos
+		c.DIRECT(target, speed, dt)
+		if d := Distance(head, c.First().Pos); d > speed*dt+1e-9 {
+			t.Fatalf("ERR: step %d: actual: %v,  expected: at most %v", i, d, speed*dt)
+		}
+	}
+	checkNoNaN(t, c)
+	checkLengths(t, c, expected, true)
+	checkBends(t, c, 1)
+}
+
+func TestSolverProperties(t *testing.T) {
+	tt := []struct {
+		name  string
+		check func(t *testing.T, c *Chain, target Point)
+	}{
+		{"FABRIK", checkFABRIK},
+		{"CCD", checkCCD},
+		{"EasyFollow", checkEasyFollow},
+		{"DIRECT", checkDIRECT},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			for i := 0; i < propertyCases; i++ {
+				c := randomChain(r.Int63(), 2+r.Intn(12), false, i%3 == 0)
+				// Mostly within reach, some well out of it
+				target := c.First().Pos.Add(Point{r.NormFloat64() * 0.6 * c.Reach(), r.NormFloat64() * 0.6 * c.Reach()})
+				tc.check(t, c, target)
+			}
+		})
+	}
+}
+
+// Seeds: a plain case, the target on the anchor, out of reach, every joint on the same spot, (where SetMag
+// falls back to pointing along X: no NaN, but not a direction that means anything), and with bend limits
+func fuzzSeeds(f *testing.F) {
+	f.Add(int64(1), uint8(4), false, false, 60.0, 40.0)
+	f.Add(int64(2), uint8(3), false, false, 0.0, 0.0)
+	f.Add(int64(3), uint8(6), false, false, -900.0, 300.0)
+	f.Add(int64(4), uint8(5), true, false, 50.0, 0.0)
+	f.Add(int64(5), uint8(0), true, false, 0.0, 0.0)
+	f.Add(int64(6), uint8(2), true, false, 1e4, -1e4)
+	f.Add(int64(7), uint8(4), false, true, -60.0, 10.0)
+	f.Add(int64(8), uint8(6), true, true, 30.0, 30.0)
+	f.Add(int64(9), uint8(3), false, true, 500.0, 0.0)
+}
+
+func FuzzFABRIK(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, seed int64, joints uint8, coincident, limited bool, dx, dy float64) {
+		if c, target, ok := fuzzCase(seed, joints, coincident, limited, dx, dy); ok {
+			checkFABRIK(t, c, target)
+		}
+	})
+}
+
+func FuzzCCDIK(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, seed int64, joints uint8, coincident, limited bool, dx, dy float64) {
+		if c, target, ok := fuzzCase(seed, joints, coincident, limited, dx, dy); ok {
+			checkCCD(t, c, target)
+		}
+	})
+}
+
+func FuzzEasyFollow(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, seed int64, joints uint8, coincident, limited bool, dx, dy float64) {
+		if c, target, ok := fuzzCase(seed, joints, coincident, limited, dx, dy); ok {
+			checkEasyFollow(t, c, target)
+		}
+	})
+}
+
+func FuzzDIRECT(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, seed int64, joints uint8, coincident, limited bool, dx, dy float64) {
+		if c, target, ok := fuzzCase(seed, joints, coincident, limited, dx, dy); ok {
+			checkDIRECT(t, c, target)
+		}
+	})
+}
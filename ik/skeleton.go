@@ -0,0 +1,225 @@
+package ik
+
+import "math"
+
+// A tree of chains for whole-body IK. The root chain (e.g. the spine) is rooted at one of its joints, and
+// every branch (legs, neck, tail...) starts at a fixed offset from a joint of a chain already in the skeleton
+type Skeleton struct {
+	root     *Chain
+	rootIdx  int
+	branches []*branch
+	targets  map[*Joint]target
+	anchored bool
+	anchor   Point
+}
+
+type branch struct {
+	chain        *Chain
+	parent       *Chain
+	at           int
+	angleOffset  float64
+	lengthOffset float64
+}
+
+type target struct {
+	pos    Point
+	weight float64
+}
+
+// A joint's wanted position after the backward stage, weighted by the targets pulling it
+type candidate struct {
+	pos    Point
+	weight float64
+}
+
+func SkeletonNew(root *Chain, rootIdx int) *Skeleton {
+	return &Skeleton{root: root, rootIdx: rootIdx, targets: map[*Joint]target{}}
+}
+
+// Attach a chain, so its first joint sits at parent.AdjustedPos(at, angleOffset, lengthOffset).
+// The parent must already be in the skeleton
+func (s *Skeleton) Attach(chain, parent *Chain, at int, angleOffset, lengthOffset float64) {
+	s.branches = append(s.branches, &branch{chain, parent, at, angleOffset, lengthOffset})
+}
+
+// Pull the joint towards p. Where targets compete, the heavier ones win
+func (s *Skeleton) SetTarget(j *Joint, p Point, weight float64) {
+	s.targets[j] = target{p, weight}
+}
+
+func (s *Skeleton) ClearTarget(j *Joint) {
+	delete(s.targets, j)
+}
+
+func (s *Skeleton) ClearTargets() {
+	s.targets = map[*Joint]target{}
+}
+
+// Keep the root joint at p. An unpinned skeleton is moved as a whole by its targets
+func (s *Skeleton) Pin(p Point) {
+	s.anchored = true
+	s.anchor = p
+}
+
+func (s *Skeleton) Unpin() {
+	s.anchored = false
+}
+
+// Iterates multi target FABRIK until all targets are within the tolerance, or the iteration cap is hit.
+// Error is the largest distance left to a target, and Reachable tells if all targets were met
+func (s *Skeleton) Solve(opts SolverOptions) SolveResult {
+	result := SolveResult{Error: s.maxError()}
+	for result.Iterations < opts.MaxIterations && (result.Iterations == 0 || result.Error > opts.Tolerance) {
+		s.FABRIK()
+		result.Iterations++
+		result.Error = s.maxError()
+	}
+	result.Reachable = result.Error <= opts.Tolerance
+	return result
+}
+
+func (s *Skeleton) maxError() float64 {
+	e := 0.0
+	for j, t := range s.targets {
+		e = math.Max(e, Distance(j.Pos, t.pos))
+	}
+	return e
+}
+
+// One backward and one forward pass over the whole tree
+func (s *Skeleton) FABRIK() {
+	// Backward: leaves first, so every branch has placed its first joint before its parent is handled
+	bases := map[*Chain]candidate{}
+	for i := len(s.branches) - 1; i >= 0; i-- {
+		b := s.branches[i]
+		if c, ok := s.backward(b.chain, 0, bases); ok {
+			bases[b.chain] = c
+		}
+	}
+	root, ok := s.backward(s.root, s.rootIdx, bases)
+	if s.anchored {
+		s.root.Joints[s.rootIdx].Pos = s.anchor
+	} else if ok {
+		s.root.Joints[s.rootIdx].Pos = root.pos
+	}
+
+	// Forward: the root chain out from the root joint, then each branch from its offset
+	s.forward(s.root, s.rootIdx)
+	for _, b := range s.branches {
+		parent := b.parent.Joints[b.at]
+		b.chain.BaseAngle = parent.Angle + b.angleOffset
+		b.chain.Joints[0].Pos = parent.AdjustedPos(b.angleOffset, b.lengthOffset)
+		s.forward(b.chain, 0)
+	}
+}
+
+// Reach backwards through the chain from both ends towards joint r. Every joint is placed at the weighted
+// centroid of what its children, branches and own target want. Returns where joint r wants to be
+func (s *Skeleton) backward(c *Chain, r int, bases map[*Chain]candidate) (candidate, bool) {
+	n := len(c.Joints)
+	wanted := make([]candidate, n)
+	active := make([]bool, n)
+
+	// What the active children, the branches and the own target of joint i want
+	gather := func(i int, children ...int) []candidate {
+		var cands []candidate
+		for _, child := range children {
+			if child >= 0 && child < n && active[child] {
+				link := c.Joints[min(i, child)].Distance
+				pos := SetConstraint(c.Joints[i].Pos, c.Joints[child].Pos, link)
+				cands = append(cands, candidate{pos, wanted[child].weight})
+			}
+		}
+		cands = append(cands, s.branchCandidates(c, i, bases)...)
+		if t, ok := s.targets[c.Joints[i]]; ok {
+			cands = []candidate{{t.pos, t.weight + totalWeight(cands)}}
+		}
+		return cands
+	}
+	place := func(i, child int) {
+		cands := gather(i, child)
+		if len(cands) == 0 {
+			return
+		}
+		wanted[i] = centroid(cands)
+		active[i] = true
+		c.Joints[i].Pos = wanted[i].pos
+	}
+
+	// Tail side, from the last joint in to the root
+	for i := n - 1; i > r; i-- {
+		place(i, i+1)
+	}
+	// Head side, from the first joint in to the root
+	for i := 0; i < r; i++ {
+		place(i, i-1)
+	}
+	// The root joint has children on both sides
+	cands := gather(r, r-1, r+1)
+	if len(cands) == 0 {
+		return candidate{}, false
+	}
+	return centroid(cands), true
+}
+
+// Where the branches at joint i of the chain want the joint to be, (their first joint less the offset)
+func (s *Skeleton) branchCandidates(c *Chain, i int, bases map[*Chain]candidate) []candidate {
+	var cands []candidate
+	for _, b := range s.branches {
+		base, ok := bases[b.chain]
+		if b.parent != c || b.at != i || !ok {
+			continue
+		}
+		j := c.Joints[i]
+		offset := j.AdjustedPos(b.angleOffset, b.lengthOffset).Sub(j.Pos)
+		cands = append(cands, candidate{base.pos.Sub(offset), base.weight})
+	}
+	return cands
+}
+
+// Reach forward from joint r out to both ends, keeping the distances and bend limits
+func (s *Skeleton) forward(c *Chain, r int) {
+	n := len(c.Joints)
+	for i := r + 1; i < n; i++ {
+		prev := c.Joints[i-1]
+		c.Joints[i].Pos = SetConstraint(c.Joints[i].Pos, prev.Pos, prev.Distance)
+		// Only branches have a base angle to measure the first joint's bend from
+		if i-1 > 0 || c != s.root {
+			c.limit(i - 1)
+		}
+	}
+	for i := r - 1; i >= 0; i-- {
+		next := c.Joints[i+1]
+		curr := c.Joints[i]
+		curr.Pos = SetConstraint(curr.Pos, next.Pos, curr.Distance)
+		if i+2 < n {
+			curr.Pos = next.limitParent(angle(c.Joints[i+2].Pos.Sub(next.Pos)), curr.Pos)
+		}
+	}
+	c.updateAngles()
+}
+
+func centroid(cands []candidate) candidate {
+	sum := Point{}
+	w := totalWeight(cands)
+	for _, c := range cands {
+		sum = sum.Add(Point{c.pos.X * c.weight, c.pos.Y * c.weight})
+	}
+	if w <= 0 {
+		// Weightless targets: plain average
+		for _, c := range cands {
+			sum = sum.Add(c.pos)
+		}
+		n := float64(len(cands))
+		return candidate{Point{sum.X / n, sum.Y / n}, 0}
+	}
+	return candidate{Point{sum.X / w, sum.Y / w}, w}
+}
+
+func totalWeight(cands []candidate) float64 {
+	w := 0.0
+	for _, c := range cands {
+		w += c.weight
+	}
+	return w
+}
@@ -0,0 +1,67 @@
+package ik
+
+import (
+	"math"
+	"testing"
+)
+
+// A spine with a leg on each side of the middle joint
+func testSkeleton() (*Skeleton, *Chain, *Chain, *Chain) {
+	spine := ChainNew([]int{10, 10, 10, 10, 10}, 0, 0, 40)
+	left := ChainNew([]int{5, 5, 5}, 0, 0, 30)
+	right := ChainNew([]int{5, 5, 5}, 0, 0, 30)
+	s := SkeletonNew(spine, 2)
+	s.Attach(left, spine, 2, -math.Pi/2, 0)
+	s.Attach(right, spine, 2, math.Pi/2, 0)
+	s.Pin(spine.Joints[2].Pos)
+	s.FABRIK()
+	return s, spine, left, right
+}
+
+func TestSkeletonPinned(t *testing.T) {
+	s, spine, left, right := testSkeleton()
+	root := spine.Joints[2].Pos
+	s.SetTarget(left.Last(), Point{-60, -60}, 1)
+	s.SetTarget(right.Last(), Point{-60, 60}, 1)
+
+	opts := SolverOptions{MaxIterations: 50, Tolerance: 0.1}
+	res := s.Solve(opts)
+	if !res.Reachable {
+		t.Errorf("ERR: actual: %+v,  expected: reachable", res)
+	}
+	if Distance(spine.Joints[2].Pos, root) > 1e-9 {
+		t.Errorf("ERR: actual: %v,  expected: %v", spine.Joints[2].Pos, root)
+	}
+	for _, c := range []*Chain{spine, left, right} {
+		for i := 1; i < len(c.Joints); i++ {
+			d := Distance(c.Joints[i-1].Pos, c.Joints[i].Pos)
+			if math.Abs(d-c.Joints[i-1].Distance) > 1e-9 {
+				t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, c.Joints[i-1].Distance)
+			}
+		}
+	}
+	// The legs stay attached at the side of the spine
+	for _, l := range []struct {
+		c      *Chain
+		offset float64
+	}{{left, -math.Pi / 2}, {right, math.Pi / 2}} {
+		expected := spine.Joints[2].AdjustedPos(l.offset, 0)
+		if Distance(l.c.First().Pos, expected) > 1e-9 {
+			t.Errorf("ERR: actual: %v,  expected: %v", l.c.First().Pos, expected)
+		}
+	}
+}
+
+func TestSkeletonFeetPullBody(t *testing.T) {
+	s, spine, left, right := testSkeleton()
+	s.Unpin()
+	before := spine.Joints[2].Pos
+
+	// Both feet far ahead: the body has to follow them
+	s.SetTarget(left.Last(), Point{300, -40}, 1)
+	s.SetTarget(right.Last(), Point{300, 40}, 1)
+	s.Solve(SolverOptions{MaxIterations: 50, Tolerance: 0.1})
+	if spine.Joints[2].Pos.X <= before.X+100 {
+		t.Errorf("ERR: actual: %v,  expected: pulled towards x=300", spine.Joints[2].Pos)
+	}
+}
@@ -0,0 +1,62 @@
+package ik
+
+// Settings for the iterative solvers
+type SolverOptions struct {
+	MaxIterations int     // Give up after this many passes
+	Tolerance     float64 // Done when the end effector is this close to the target
+	Damping       float64 // CCD only: share of each joint's correction applied per step, (0 means 1)
+}
+
+var DefaultSolverOptions = SolverOptions{MaxIterations: 10, Tolerance: 0.5, Damping: 1}
+
+// Which solver a chain should use to reach a target
+type Solver int
+
+const (
+	SolverFABRIK Solver = iota
+	SolverCCD
+	SolverJacobianTranspose
+	SolverDLS
+)
+
+func (s Solver) String() string {
+	switch s {
+	case SolverFABRIK:
+		return "FABRIK"
+	case SolverCCD:
+		return "CCD"
+	case SolverJacobianTranspose:
+		return "JacobianTranspose"
+	case SolverDLS:
+		return "DLS"
+	}
+	return "unknown"
+}
+
+// Reach for the target with the given solver, keeping the first joint at the anchor
+func (c *Chain) Solve(solver Solver, target, anchor Point, opts SolverOptions) SolveResult {
+	jopts := DefaultJacobianOptions
+	jopts.SolverOptions = opts
+	switch solver {
+	case SolverCCD:
+		return c.SolveCCDIK(target, anchor, opts)
+	case SolverJacobianTranspose:
+		return c.SolveJacobianTranspose(Goal{Pos: target}, anchor, jopts)
+	case SolverDLS:
+		return c.SolveDLS(Goal{Pos: target}, anchor, jopts)
+	}
+	return c.SolveFABRIK(target, anchor, opts)
+}
+
+// What an iterative solver achieved
+type SolveResult struct {
+	Iterations int     // Passes used
+	Error      float64 // Distance left between the end effector and the target
+	Reachable  bool    // False if the target was out of reach of the chain
+	AngleError float64 // Orientation left, (Jacobian solvers with an oriented goal only)
+}
+
+// True if the end effector ended up within the tolerance
+func (r SolveResult) Converged(opts SolverOptions) bool {
+	return r.Error <= opts.Tolerance
+}
@@ -0,0 +1,76 @@
+package ik
+
+import "math"
+
+// Settings for the Verlet mode of a chain, (see SetVerlet)
+type VerletOptions struct {
+	Gravity    Point   // Pixels per second squared
+	Damping    float64 // Share of the velocity lost per second, 0 to 1
+	Stiffness  float64 // How hard each segment is pulled in line with the one before: 0 is a rope, 1 a rod
+	Iterations int     // Constraint passes per step
+}
+
+var DefaultVerletOptions = VerletOptions{Damping: 0.9, Stiffness: 0.1, Iterations: 4}
+
+// Let the joints behind the head move by Verlet integration, (so they swing and settle with inertia),
+// instead of following directly. nil goes back to following directly
+func (c *Chain) SetVerlet(opts *VerletOptions) {
+	c.verlet = opts
+	c.prev = nil
+}
+
+// Move the joints behind the head dt seconds on, by their own velocity and gravity. Then pull them back
+// to their distances, and towards the direction of the segment before, (by the stiffness)
+func (c *Chain) Verlet(dt float64, opts VerletOptions) {
+	joints := c.Joints
+	if len(c.prev) != len(joints) {
+		// Start at rest
+		c.prev = make([]Point, len(joints))
+		for i, j := range joints {
+			c.prev[i] = j.Pos
+		}
+	}
+
+	// Integrate: the velocity is how far the joint moved during the last step
+	keep := math.Pow(1-math.Min(1, math.Max(0, opts.Damping)), dt)
+	for i := 1; i < len(joints); i++ {
+		pos := joints[i].Pos
+		vel := pos.Sub(c.prev[i])
+		c.prev[i] = pos
+		joints[i].Pos = Point{
+			pos.X + vel.X*keep + opts.Gravity.X*dt*dt,
+			pos.Y + vel.Y*keep + opts.Gravity.Y*dt*dt,
+		}
+	}
+	c.prev[0] = joints[0].Pos
+
+	// Satisfy the constraints, from the head backwards
+	for it := 0; it < max(1, opts.Iterations); it++ {
+		for i := 1; i < len(joints); i++ {
+			parent := joints[i-1]
+			curr := joints[i]
+			if i > 1 && opts.Stiffness > 0 {
+				dir := parent.Pos.Sub(joints[i-2].Pos).SetMag(parent.Distance)
+				straight := parent.Pos.Add(dir)
+				curr.Pos = Point{lerp(opts.Stiffness, curr.Pos.X, straight.X), lerp(opts.Stiffness, curr.Pos.Y, straight.Y)}
+			}
+			curr.Pos = SetConstraint(curr.Pos, parent.Pos, parent.Distance)
+			if i > 1 {
+				c.limit(i - 1)
+			}
+		}
+	}
+	for i := 1; i < len(joints); i++ {
+		joints[i].Angle = joints[i-1].Pos.Angle(joints[i].Pos)
+	}
+}
+
+// Where the joints were the step before, (their velocity, in Verlet mode). Empty until the first Verlet step
+func (c *Chain) Previous() []Point {
+	return c.prev
+}
+
+// Put back where the joints were the step before, (e.g. from a snapshot). Empty starts the joints at rest
+func (c *Chain) SetPrevious(prev []Point) {
+	c.prev = append([]Point(nil), prev...)
+}
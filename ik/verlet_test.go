@@ -0,0 +1,49 @@
+package ik
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVerletHangs(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10, 10}, 0, 0, 20)
+	opts := VerletOptions{Gravity: Point{0, 980}, Damping: 0.9, Iterations: 4}
+	for i := 0; i < 600; i++ {
+		c.Verlet(1.0/60, opts)
+	}
+	tail := c.Last().Pos
+	if math.Abs(tail.X) > 1 || math.Abs(tail.Y-80) > 1 {
+		t.Errorf("ERR: actual: %v,  expected: %v", tail, Point{0, 80})
+	}
+	for i := 1; i < len(c.Joints); i++ {
+		d := Distance(c.Joints[i-1].Pos, c.Joints[i].Pos)
+		if math.Abs(d-20) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, 20)
+		}
+	}
+}
+
+func TestVerletKeepsMoving(t *testing.T) {
+	c := ChainNew([]int{10, 10, 10, 10, 10}, 0, 0, 20)
+	c.SetVerlet(&VerletOptions{Damping: 0.5, Stiffness: 0.2, Iterations: 4})
+	// Swing the head up and down while moving right, then stop it
+	for i := 0; i < 120; i++ {
+		c.First().Pos = Point{float64(i) * 2, 40 * math.Sin(float64(i)/10)}
+		c.Verlet(1.0/60, *c.verlet)
+	}
+	before := c.Last().Pos
+	c.Verlet(1.0/60, *c.verlet)
+	if moved := Distance(before, c.Last().Pos); moved < 0.5 {
+		t.Errorf("ERR: actual: %v,  expected: the tail to keep moving", moved)
+	}
+
+	// And it settles
+	for i := 0; i < 600; i++ {
+		c.Verlet(1.0/60, *c.verlet)
+	}
+	before = c.Last().Pos
+	c.Verlet(1.0/60, *c.verlet)
+	if moved := Distance(before, c.Last().Pos); moved > 0.01 {
+		t.Errorf("ERR: actual: %v,  expected: the tail to settle", moved)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const (
+	inputLogVersion = 1
+	checkTicks      = TickRate // How often the joints' checksum is logged
+)
+
+// The inputs of a run, to replay it exactly: the flags and seed it was started with, the target of every tick,
+// and checksums of the joints along the way
+type InputLog struct {
+	Args    []string // Flags, as "-name=value"
+	Seed    int64
+	Start   int            // The tick the run started at, (later than 0 when resumed from a snapshot)
+	Targets []ik.Point     // The target of each tick from the start, in order
+	Checks  map[int]uint64 // Checksum of the joints, after that many ticks, (counted from 0, not the start)
+}
+
+// The replayed joints differ from the recorded ones, after Tick ticks
+type DivergenceError struct {
+	Tick             int
+	Expected, Actual uint64
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("diverged after tick %d: checksum %016x, recorded %016x", e.Tick, e.Actual, e.Expected)
+}
+
+// A hash of the exact positions and angles of every joint
+func Checksum(creature Creature) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	put := func(v float64) {
+		bits := math.Float64bits(v)
+		for i := range buf {
+			buf[i] = byte(bits >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+	for _, chain := range creature.chains() {
+		for _, j := range chain.Joints {
+			put(j.Pos.X)
+			put(j.Pos.Y)
+			put(j.Angle)
+		}
+	}
+	return h.Sum64()
+}
+
+// Writes the inputs of a run as they happen
+type InputRecorder struct {
+	f        *os.File
+	w        *bufio.Writer
+	creature Creature
+	start    int
+	ticks    int
+}
+
+// Start a log of the run of the creature, from the start tick. The args and the seed are what it takes to build
+// the same creature again
+func InputRecorderNew(path string, args []string, seed int64, start int, creature Creature) (*InputRecorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &InputRecorder{f: f, w: bufio.NewWriter(f), creature: creature, start: start, ticks: start}
+	fmt.Fprintf(r.w, "moving_snakes input %d\n", inputLogVersion)
+	fmt.Fprintf(r.w, "seed %d\n", seed)
+	fmt.Fprintf(r.w, "start %d\n", start)
+	fmt.Fprint(r.w, "args")
+	for _, a := range args {
+		fmt.Fprintf(r.w, " %s", strconv.Quote(a))
+	}
+	fmt.Fprintf(r.w, "\ndt %s\n", exact(TickDuration))
+	// (so a replay finds out right away if it doesn't start from the same place)
+	fmt.Fprintf(r.w, "check %d %016x\n", start, Checksum(creature))
+	return r, nil
+}
+
+// Log the targets of the path as the creature is stepped along it, (and the checksums between the steps)
+func (r *InputRecorder) Path(path TargetPath) TargetPath {
+	return func(tick int) ik.Point {
+		if tick > r.start && tick%checkTicks == 0 {
+			fmt.Fprintf(r.w, "check %d %016x\n", tick, Checksum(r.creature))
+		}
+		p := path(tick)
+		fmt.Fprintf(r.w, "t %s %s\n", exact(p.X), exact(p.Y))
+		r.ticks = tick + 1
+		return p
+	}
+}
+
+// Log the checksum of where the creature ended up, and close the file
+func (r *InputRecorder) Close() error {
+	fmt.Fprintf(r.w, "check %d %016x\n", r.ticks, Checksum(r.creature))
+	if err := r.w.Flush(); err != nil {
+		r.f.Close()
+		return err
+	}
+	return r.f.Close()
+}
+
+// A float in hex, (every bit of it)
+func exact(v float64) string {
+	return strconv.FormatFloat(v, 'x', -1, 64)
+}
+
+func LoadInputLog(path string) (*InputLog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	in, err := ParseInputLog(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return in, nil
+}
+
+func ParseInputLog(r io.Reader) (*InputLog, error) {
+	in := &InputLog{Checks: map[int]uint64{}}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	bad := func(format string, args ...any) error {
+		return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+	}
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case line == 1:
+			if len(fields) != 3 || fields[0] != "moving_snakes" || fields[1] != "input" {
+				return nil, bad("not an input log")
+			}
+			if v, err := strconv.Atoi(fields[2]); err != nil || v != inputLogVersion {
+				return nil, bad("version %s, want %d", fields[2], inputLogVersion)
+			}
+		case fields[0] == "seed" && len(fields) == 2:
+			v, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, bad("%v", err)
+			}
+			in.Seed = v
+		case fields[0] == "start" && len(fields) == 2:
+			v, err := strconv.Atoi(fields[1])
+			if err != nil || v < 0 {
+				return nil, bad("bad start %s", fields[1])
+			}
+			in.Start = v
+		case fields[0] == "args":
+			rest := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "args"))
+			for rest != "" {
+				q, err := strconv.QuotedPrefix(rest)
+				if err != nil {
+					return nil, bad("%v", err)
+				}
+				a, _ := strconv.Unquote(q)
+				in.Args = append(in.Args, a)
+				rest = strings.TrimSpace(rest[len(q):])
+			}
+		case fields[0] == "dt" && len(fields) == 2:
+			if dt, err := strconv.ParseFloat(fields[1], 64); err != nil || dt != TickDuration {
+				return nil, bad("recorded with steps of %s seconds, these are %s", fields[1], exact(TickDuration))
+			}
+		case fields[0] == "t" && len(fields) == 3:
+			x, err1 := strconv.ParseFloat(fields[1], 64)
+			y, err2 := strconv.ParseFloat(fields[2], 64)
+			if err1 != nil || err2 != nil {
+				return nil, bad("bad target %v", fields[1:])
+			}
+			in.Targets = append(in.Targets, ik.Point{X: x, Y: y})
+		case fields[0] == "check" && len(fields) == 3:
+			tick, err1 := strconv.Atoi(fields[1])
+			sum, err2 := strconv.ParseUint(fields[2], 16, 64)
+			if err1 != nil || err2 != nil {
+				return nil, bad("bad check %v", fields[1:])
+			}
+			in.Checks[tick] = sum
+		default:
+			return nil, bad("unknown entry %q", fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line == 0 {
+		return nil, fmt.Errorf("empty input log")
+	}
+	return in, nil
+}
+
+// Step the creature, (built the same way as the recorded one, and at the start), through the recorded targets. A
+// *DivergenceError at the first checksum that differs
+func (l *InputLog) Replay(creature Creature) error {
+	sim := SimulatorNew(creature, func(tick int) ik.Point { return l.Targets[tick-l.Start] })
+	sim.tick = l.Start
+	end := l.Start + len(l.Targets)
+	for tick := l.Start; tick <= end; tick++ {
+		if expected, ok := l.Checks[tick]; ok {
+			if actual := Checksum(creature); actual != expected {
+				return &DivergenceError{Tick: tick, Expected: expected, Actual: actual}
+			}
+		}
+		if tick < end {
+			sim.Step()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestInputReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.log")
+	lizard := LizardNew(600, 400)
+	rec, err := InputRecorderNew(path, []string{"-kind=lizard", "-gait=walk"}, 7, 0, lizard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := SimulatorNew(lizard, rec.Path(RoamPath(lizard, 7)))
+	for i := 0; i < 250; i++ {
+		sim.Step()
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := LoadInputLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Seed != 7 || strings.Join(in.Args, " ") != "-kind=lizard -gait=walk" || len(in.Targets) != 250 {
+		t.Fatalf("ERR: actual: %v %v %v,  expected: %v %v %v", in.Seed, in.Args, len(in.Targets), 7, "[-kind=lizard -gait=walk]", 250)
+	}
+	// The same lizard ends up exactly the same, without the roaming that steered the first one
+	replayed := LizardNew(600, 400)
+	if err := in.Replay(replayed); err != nil {
+		t.Errorf("ERR: actual: %v,  expected: no divergence", err)
+	}
+	if Checksum(replayed) != Checksum(lizard) {
+		t.Errorf("ERR: actual: %016x,  expected: %016x", Checksum(replayed), Checksum(lizard))
+	}
+
+	// Nudging one target is caught at the next check
+	in.Targets[100].X += 1e-9
+	var div *DivergenceError
+	if err := in.Replay(LizardNew(600, 400)); !errors.As(err, &div) || div.Tick != 120 {
+		t.Errorf("ERR: actual: %v,  expected: a divergence after tick %v", err, 120)
+	}
+}
+
+func TestInputLogErrors(t *testing.T) {
+	tt := []string{
+		"",
+		"moving_snakes input 2\n",
+		"moving_snakes input 1\ndt 0x1p-05\n",
+		"moving_snakes input 1\nt 1 2\njump 3\n",
+		"moving_snakes input 1\nargs \"-kind=snake\n",
+	}
+	for _, tc := range tt {
+		if _, err := ParseInputLog(strings.NewReader(tc)); err == nil {
+			t.Errorf("ERR: actual: %v,  expected: an error for %q", err, tc)
+		}
+	}
+}
+
+// A run resumed from a snapshot replays from the same snapshot, (and not without it)
+func TestInputReplayFromSnapshot(t *testing.T) {
+	lizard := LizardNew(600, 400)
+	step(SimulatorNew(lizard, WaypointPath([]ik.Point{{X: 1800, Y: 400}}, 1)), 150)
+	snap := TakeSnapshot(lizard, 150)
+
+	path := filepath.Join(t.TempDir(), "input.log")
+	resumed := LizardNew(600, 400)
+	if err := snap.Restore(resumed); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := InputRecorderNew(path, []string{"-snapshot=lizard.bin"}, 7, snap.Tick, resumed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := SimulatorNew(resumed, rec.Path(RoamPath(resumed, 7)))
+	sim.tick = snap.Tick
+	step(sim, 200)
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := LoadInputLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Start != 150 || len(in.Targets) != 200 {
+		t.Fatalf("ERR: actual: %v %v,  expected: %v %v", in.Start, len(in.Targets), 150, 200)
+	}
+	replayed := LizardNew(600, 400)
+	if err := snap.Restore(replayed); err != nil {
+		t.Fatal(err)
+	}
+	if err := in.Replay(replayed); err != nil {
+		t.Errorf("ERR: actual: %v,  expected: no divergence", err)
+	}
+	if Checksum(replayed) != Checksum(resumed) {
+		t.Errorf("ERR: actual: %016x,  expected: %016x", Checksum(replayed), Checksum(resumed))
+	}
+	var div *DivergenceError
+	if err := in.Replay(LizardNew(600, 400)); !errors.As(err, &div) || div.Tick != 150 {
+		t.Errorf("ERR: actual: %v,  expected: a divergence at tick %v", err, 150)
+	}
+}
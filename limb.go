@@ -5,73 +5,157 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/mpja69/moving_snakes/ik"
+	"github.com/mpja69/moving_snakes/terrain"
+)
+
+const (
+	elbowMaxBend  = math.Pi * 0.8
+	shoulderInset = -14     // How far inside the body's outline the shoulder sits
+	stepDuration  = 1.0 / 6 // Seconds
+	stepLift      = 20
 )
 
 type Limb struct {
-	chain       *Chain
-	anchorJoint *Joint
-	footPos     Point
+	chain       *ik.Chain
+	anchorJoint *ik.Joint
+	footPos     ik.Point
 	rightSide   bool
 	frontSide   bool
 	maxLength   float64
+	footAngle   float64          // From the anchor's heading out to the foothold
+	footReach   float64          // Share of maxLength out to the foothold
+	stepAt      float64          // How far from the anchor joint the foot may get, before it steps
+	ground      *terrain.Terrain // Where the feet may be put down, (anywhere when nil)
+	solver      ik.Solver
+	solve       ik.SolveResult
+	skeleton    *ik.Skeleton
+	step        Stepper
 	vertices    []ebiten.Vertex
 	indices     []uint16
 }
 
-func LimbNew(anchorJoint *Joint, distance int, rightSide, frontSide bool) *Limb {
-	joints := []int{20, 40, 30}
-	chain := ChainNew(joints, 0, 0, distance)
+// The limb's joints get the given radii, shoulder first
+func LimbNew(anchorJoint *ik.Joint, joints []int, distance int, rightSide, frontSide bool) *Limb {
+	chain := ik.ChainNew(joints, 0, 0, distance)
 	maxLength := float64(len(joints) * distance)
-	return &Limb{chain: chain, anchorJoint: anchorJoint, rightSide: rightSide, frontSide: frontSide, maxLength: maxLength}
+
+	// Elbows, (and knees of longer legs), only bend one way: clockwise on the right side, counter clockwise on the left
+	for _, elbow := range chain.Joints[1 : len(joints)-1] {
+		if rightSide {
+			elbow.SetAngleLimits(-elbowMaxBend, 0)
+		} else {
+			elbow.SetAngleLimits(0, elbowMaxBend)
+		}
+	}
+	// Fore limbs reach far forward, hind limbs less far and more to the side
+	footAngle, footReach := math.Pi/8, 1.0
+	if !frontSide {
+		footAngle, footReach = math.Pi/4, 0.5
+	}
+	step := StepperNew(stepDuration, stepLift, EaseInOutSine)
+	return &Limb{chain: chain, anchorJoint: anchorJoint, rightSide: rightSide, frontSide: frontSide, maxLength: maxLength, footAngle: footAngle, footReach: footReach, stepAt: maxLength / 0.7, step: step}
+}
+
+// Where the foot is put down: the angle from the anchor's heading, (towards the limb's side), and the share of the limb's length out
+func (l *Limb) SetFoothold(angle, reach float64) {
+	l.footAngle = angle
+	l.footReach = reach
+}
+
+// Take a step when the foot gets further than this from the anchor joint
+func (l *Limb) SetStepDistance(d float64) {
+	l.stepAt = d
+}
+
+// Only put the foot down on walkable ground, searching near the foothold for a spot
+func (l *Limb) SetTerrain(t *terrain.Terrain) {
+	l.ground = t
+}
+
+// Choose how the limb reaches for its foot position, (FABRIK by default)
+func (l *Limb) SetSolver(solver ik.Solver) {
+	l.solver = solver
+}
+
+// Which side of the anchor joint the shoulder sits on
+func (l *Limb) shoulderAngle() float64 {
+	if l.rightSide {
+		return math.Pi / 2
+	}
+	return -math.Pi / 2
 }
 
 func (l *Limb) totalLength() float64 {
-	return distance(l.chain.first().pos, l.chain.last().pos)
+	return ik.Distance(l.chain.First().Pos, l.chain.Last().Pos)
 }
 
-// Update the limbs position and shape, and take step if it's time to move
-func (l *Limb) update() (didMove bool) {
+func (l *Limb) visitState(v stateVisitor) {
+	visitChain(v, "chain", l.chain)
+	v.point("foot", &l.footPos)
+	l.step.visitState(prefixed{v, "step."})
+}
+
+// Update the limbs position and shape, and take step if it's time to move, (and the gait allows it). dt is in seconds
+func (l *Limb) update(mayStep bool, dt float64) (didMove bool) {
 	didMove = false
-	var anchorPos Point
+	var anchorPos ik.Point
 	var moveAngle float64
-	var newFootPos Point
+	var newFootPos ik.Point
 	var length float64
 
+	length = l.maxLength * l.footReach
 	if l.rightSide {
-		if l.frontSide {
-			moveAngle = l.anchorJoint.angle + math.Pi/8 // 6
-			length = l.maxLength
-		} else {
-			moveAngle = l.anchorJoint.angle + math.Pi/4
-			length = l.maxLength * 0.5
+		moveAngle = l.anchorJoint.Angle + l.footAngle
+		newFootPos.X = length*math.Cos(moveAngle) + l.anchorJoint.Right().X
+		newFootPos.Y = length*math.Sin(moveAngle) + l.anchorJoint.Right().Y
+		anchorPos = l.anchorJoint.AdjustedPos(math.Pi/2, shoulderInset)
+	} else {
+		moveAngle = l.anchorJoint.Angle - l.footAngle
+		newFootPos.X = length*math.Cos(moveAngle) + l.anchorJoint.Left().X
+		newFootPos.Y = length*math.Sin(moveAngle) + l.anchorJoint.Left().Y
+		anchorPos = l.anchorJoint.AdjustedPos(-math.Pi/2, shoulderInset)
+	}
+
+	// On uneven terrain: the closest walkable spot, within half a leg of the foothold. Without one, the foot stays where it is
+	found := true
+	if l.ground != nil {
+		heading := ik.Point{X: math.Cos(l.anchorJoint.Angle), Y: math.Sin(l.anchorJoint.Angle)}
+		newFootPos, found = l.ground.Foothold(newFootPos, heading, l.maxLength/2)
+	}
+
+	// Swing the foot along an arc to the new foothold, (which moves along with the body during the step)
+	if l.step.Swinging() {
+		if found {
+			l.step.Retarget(newFootPos)
 		}
-		newFootPos.x = length*math.Cos(moveAngle) + l.anchorJoint.Right().x
-		newFootPos.y = length*math.Sin(moveAngle) + l.anchorJoint.Right().y
-		anchorPos = l.anchorJoint.getAdjustedPos(math.Pi/2, -14)
 	} else {
-		if l.frontSide {
-			moveAngle = l.anchorJoint.angle - math.Pi/8 //6
-			length = l.maxLength
-		} else {
-			moveAngle = l.anchorJoint.angle - math.Pi/4
-			length = l.maxLength * 0.5
+		delta := ik.Distance(l.anchorJoint.Pos, l.footPos)
+		if delta > l.stepAt && (mayStep || !l.step.Placed()) && (found || !l.step.Placed()) {
+			side := 1.0
+			if !l.rightSide {
+				side = -1
+			}
+			l.step.Start(l.footPos, newFootPos, side)
+			didMove = true
 		}
-		newFootPos.x = length*math.Cos(moveAngle) + l.anchorJoint.Left().x
-		newFootPos.y = length*math.Sin(moveAngle) + l.anchorJoint.Left().y
-		anchorPos = l.anchorJoint.getAdjustedPos(-math.Pi/2, -14)
 	}
+	l.footPos = l.step.Advance(dt)
 
-	delta := distance(l.anchorJoint.pos, l.footPos) * 0.7
-	if delta > l.maxLength {
-		l.footPos = newFootPos
-		didMove = true
+	// With a whole body skeleton, the lizard solves all limbs together with the spine. Only planted feet pull
+	if l.skeleton != nil {
+		weight := 0.0
+		if l.step.Planted() {
+			weight = footWeight
+		}
+		l.skeleton.SetTarget(l.chain.Last(), l.footPos, weight)
+		return didMove
 	}
-
-	l.chain.FABRIK(l.footPos, anchorPos)
+	l.solve = l.chain.Solve(l.solver, l.footPos, anchorPos, ik.DefaultSolverOptions)
 	return didMove
 }
 
-func (b *Limb) draw(screen *ebiten.Image) {
+func (b *Limb) draw(screen *ebiten.Image, style Style) {
 
 	path := b.createPath()
 
@@ -80,26 +164,47 @@ func (b *Limb) draw(screen *ebiten.Image) {
 
 	// Stroke options
 	sop := &vector.StrokeOptions{}
-	sop.Width = 40
+	sop.Width = float32(style.LimbWidth + 8)
 	sop.LineJoin = vector.LineJoinRound
 	sop.LineCap = vector.LineCapRound
 	b.vertices, b.indices = path.AppendVerticesAndIndicesForStroke(b.vertices[:0], b.indices[:0], sop)
+	colorVertices(b.vertices, style.Outline)
 	screen.DrawTriangles(b.vertices, b.indices, outlineSubImage, top)
 
-	sop.Width = 32
+	sop.Width = float32(style.LimbWidth)
 	sop.LineJoin = vector.LineJoinRound
 	b.vertices, b.indices = path.AppendVerticesAndIndicesForStroke(b.vertices[:0], b.indices[:0], sop)
-	screen.DrawTriangles(b.vertices, b.indices, fillSubImage, top)
+	colorVertices(b.vertices, style.Fill)
+	screen.DrawTriangles(b.vertices, b.indices, outlineSubImage, top)
+}
+
+// An outline a bit wider than the limb, and the limb on top, like draw
+func (l *Limb) sketch(s *Sketch, style Style) {
+	path := Outline{}
+	l.appendPath(&path)
+	s.stroke(path, style.LimbWidth+8, style.Outline)
+	s.stroke(path, style.LimbWidth, style.Fill)
 }
+
 func (l *Limb) createPath() *vector.Path {
 	path := vector.Path{}
+	l.appendPath(&path)
+	return &path
+}
+
+// The limb's center line, (stroked wide to draw it)
+func (l *Limb) appendPath(path pathBuilder) {
+	if len(l.chain.Joints) > 3 {
+		l.appendSegmentedPath(path)
+		return
+	}
 
-	shoulder := l.chain.joints[0].pos
-	elbow := l.chain.joints[1].pos
-	foot := l.chain.joints[2].pos
+	shoulder := l.chain.Joints[0].Pos
+	elbow := l.chain.Joints[1].Pos
+	foot := l.chain.Joints[2].Pos
 
 	para := foot.Sub(shoulder)
-	perp := Point{-para.y, para.x}.SetMag(30)
+	perp := ik.Point{X: -para.Y, Y: para.X}.SetMag(30)
 	if l.frontSide == false {
 		if l.rightSide {
 			elbow = elbow.Sub(perp)
@@ -108,11 +213,24 @@ func (l *Limb) createPath() *vector.Path {
 		}
 	}
 
-	path.MoveTo(float32(shoulder.x), float32(shoulder.y))
+	path.MoveTo(float32(shoulder.X), float32(shoulder.Y))
 	path.CubicTo(
-		float32(elbow.x), float32(elbow.y),
-		float32(elbow.x), float32(elbow.y),
-		float32(foot.x), float32(foot.y),
+		float32(elbow.X), float32(elbow.Y),
+		float32(elbow.X), float32(elbow.Y),
+		float32(foot.X), float32(foot.Y),
 	)
-	return &path
+}
+
+// Legs with more than one knee: smooth curves through the middle of each segment, bending at the knees
+func (l *Limb) appendSegmentedPath(path pathBuilder) {
+	joints := l.chain.Joints
+	path.MoveTo(float32(joints[0].Pos.X), float32(joints[0].Pos.Y))
+	for i := 1; i < len(joints)-1; i++ {
+		knee := joints[i].Pos
+		end := joints[i+1].Pos
+		if i < len(joints)-2 {
+			end = ik.Point{X: (knee.X + end.X) / 2, Y: (knee.Y + end.Y) / 2}
+		}
+		path.QuadTo(float32(knee.X), float32(knee.Y), float32(end.X), float32(end.Y))
+	}
 }
@@ -3,177 +3,172 @@ package main
 import (
 	"image/color"
 	"math"
+	"strconv"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/mpja69/moving_snakes/ik"
+	"github.com/mpja69/moving_snakes/terrain"
+)
+
+const (
+	spineMaxBend = math.Pi / 5 // How much the spine may bend at each joint
+	gaitStride   = 80          // How far the body moves during one gait cycle
+	footWeight   = 1           // How hard a planted foot pulls on the whole body skeleton
 )
 
 type Lizard struct {
-	chain *Chain
+	chain *ik.Chain
 	limbs []*Limb
+	gait  *Gait
 	speed float64
+	// Solves the spine and all limbs together, when set
+	skeleton *ik.Skeleton
+	collide  bool // Keep the body from passing through itself
+	ground   *terrain.Terrain
 	// To draw
+	style    Style
 	vertices []ebiten.Vertex
 	indices  []uint16
 }
 
 // Loop to create segments. Head first, tail last
 func LizardNew(x, y int) *Lizard {
-	bodyShape := []int{52, 58, 40, 60, 68, 71, 65, 50, 28, 15, 11, 9, 7, 7, 7}
-	chain := ChainNew(bodyShape, x, y, 64)
+	b, err := LizardFromDefinition(&LizardDefinition, x, y)
+	if err != nil {
+		panic(err) // The built in definition is always valid
+	}
+	return b
+}
 
-	limbs := make([]*Limb, 0, 4)
-	limbs = append(limbs, LimbNew(chain.joints[3], 40, true, true))
-	limbs = append(limbs, LimbNew(chain.joints[7], 40, false, false))
-	limbs = append(limbs, LimbNew(chain.joints[3], 40, false, true))
-	limbs = append(limbs, LimbNew(chain.joints[7], 40, true, false))
+// Update all segments of the body, dt seconds on
+func (b *Lizard) update(target ik.Point, dt float64) {
 
-	return &Lizard{chain: chain, limbs: limbs, vertices: []ebiten.Vertex{}, indices: []uint16{}, speed: 2}
+	// Update the body (with each joint directly follow eachother)
+	b.chain.DIRECT(target, b.speed, dt)
+	if b.collide {
+		ik.Collide([]*ik.Chain{b.chain}, ik.DefaultCollisionOptions)
+	}
+	if b.ground != nil {
+		keepOut(b.ground, b.chain)
+	}
+
+	// Update each limb, (using FABRIK), in the order of the gait
+	b.gait.update(b.speed, dt)
+
+	// Let the planted feet pull on the body, while the head keeps leading
+	if b.skeleton != nil {
+		head := b.chain.First()
+		heading := head.Angle
+		b.skeleton.Pin(head.Pos)
+		res := b.skeleton.Solve(ik.DefaultSolverOptions)
+		head.Angle = heading
+		for _, l := range b.limbs {
+			l.solve = ik.SolveResult{Iterations: res.Iterations, Error: ik.Distance(l.chain.Last().Pos, l.footPos), Reachable: res.Reachable}
+		}
+	}
 }
 
-// Update all segments of the body
-func (b *Lizard) update(target Point) {
+// Solve the spine and the limbs as one skeleton, so the feet affect the body. Or each limb on its own, (the default)
+func (b *Lizard) SetWholeBody(on bool) {
+	b.skeleton = nil
+	if on {
+		b.skeleton = ik.SkeletonNew(b.chain, 0)
+		for _, l := range b.limbs {
+			b.skeleton.Attach(l.chain, b.chain, b.jointIndex(l.anchorJoint), l.shoulderAngle(), shoulderInset)
+		}
+	}
+	for _, l := range b.limbs {
+		l.skeleton = b.skeleton
+	}
+}
 
-	// Update the body (with each joint directly follow eachother)
-	b.chain.DIRECT(target, b.speed)
+// Push the tail out of the body in tight turns, instead of letting it fold across
+func (b *Lizard) SetSelfCollision(on bool) {
+	b.collide = on
+}
 
-	// Update each limb, (using FABRIK)
+// Keep the body out of the terrain's obstacles, and the feet on walkable ground
+func (b *Lizard) SetTerrain(t *terrain.Terrain) {
+	b.ground = t
 	for _, l := range b.limbs {
-		l.update()
+		l.SetTerrain(t)
+	}
+}
+
+func (b *Lizard) jointIndex(j *ik.Joint) int {
+	for i, bj := range b.chain.Joints {
+		if bj == j {
+			return i
+		}
 	}
+	return -1
 }
 
 func (b *Lizard) debugDraw(screen *ebiten.Image) {
-	for _, j := range b.chain.joints {
-		j.DrawCircle(screen)
+	for _, j := range b.chain.Joints {
+		drawJointCircle(screen, j)
 	}
 	for _, l := range b.limbs {
-		for _, j := range l.chain.joints {
-			j.DrawCircle(screen)
-			x0 := j.pos.x
-			y0 := j.pos.y
-			x1 := j.pos.x + math.Cos(j.angle)*(j.radius)
-			y1 := j.pos.y + math.Sin(j.angle)*(j.radius)
+		for _, j := range l.chain.Joints {
+			drawJointCircle(screen, j)
+			x0 := j.Pos.X
+			y0 := j.Pos.Y
+			x1 := j.Pos.X + math.Cos(j.Angle)*(j.Radius)
+			y1 := j.Pos.Y + math.Sin(j.Angle)*(j.Radius)
 			ebitenutil.DrawLine(screen, x0, y0, x1, y1, color.White)
 
 		}
+		// Show where the foot wanted to go, when the leg couldn't get there
+		if !l.solve.Converged(ik.DefaultSolverOptions) {
+			foot := l.chain.Last().Pos
+			ebitenutil.DrawLine(screen, foot.X, foot.Y, l.footPos.X, l.footPos.Y, color.RGBA{255, 0, 0, 255})
+		}
 	}
 }
 
 func (b *Lizard) createPath() *vector.Path {
-	// Create the path clockwise around the whole body
-	path := vector.Path{}
-
-	chain := b.chain
-
-	// Move to start point: First point
-	path.MoveTo(float32(chain.first().Right().x), float32(chain.first().Right().y))
-
-	// Draw the right side. p1 only used for getting control points, and move through p2 an p3. Take 2 steps in the loop
-	for i := 0; i < len(chain.joints)-2; i += 1 {
-		prev := chain.joints[i].Right()
-		curr := chain.joints[i+1].Right()
-		next := chain.joints[i+2].Right()
-		interpolateBezierVertices(&path, prev, curr, next)
-	}
+	return chainPath(b.chain)
+}
 
-	// Draw the tail
-	tail := len(chain.joints) - 1
-	prev := chain.joints[tail-1].Right()
-	curr := chain.joints[tail].Right()
-	next := Point{chain.getAdjustedPosX(tail, math.Pi, 20), chain.getAdjustedPosY(tail, math.Pi, 20)}
-	interpolateBezierVertices(&path, prev, curr, next)
-
-	prev = chain.joints[tail].Right()
-	curr = Point{chain.getAdjustedPosX(tail, math.Pi, 20), chain.getAdjustedPosY(tail, math.Pi, 20)}
-	next = chain.joints[tail].Left()
-	interpolateBezierVertices(&path, prev, curr, next)
-
-	prev = Point{chain.getAdjustedPosX(tail, math.Pi, 20), chain.getAdjustedPosY(tail, math.Pi, 20)}
-	curr = chain.joints[tail].Left()
-	next = chain.joints[tail-1].Left()
-	interpolateBezierVertices(&path, prev, curr, next)
-
-	// Draw the left side
-	for i := len(chain.joints) - 1; i > 1; i -= 1 {
-		prev := chain.joints[i].Left()
-		curr := chain.joints[i-1].Left()
-		next := chain.joints[i-2].Left()
-		interpolateBezierVertices(&path, prev, curr, next)
+func (b *Lizard) draw(screen *ebiten.Image) {
+	for _, l := range b.limbs {
+		l.draw(screen, b.style)
 	}
 
-	// Draw the head
-	prev = chain.joints[1].Left()
-	curr = chain.joints[0].Left()
-	next = Point{chain.getAdjustedPosX(0, -math.Pi/6, -8), chain.getAdjustedPosY(0, -math.Pi/6, -10)}
-	interpolateBezierVertices(&path, prev, curr, next)
-
-	// Top of the head (completes the loop)
-	p1 := Point{chain.getAdjustedPosX(0, -math.Pi/6, -8), chain.getAdjustedPosY(0, -math.Pi/6, -10)}
-	p2 := Point{chain.getAdjustedPosX(0, 0, -6), chain.getAdjustedPosY(0, 0, -4)}
-	p3 := Point{chain.getAdjustedPosX(0, math.Pi/6, -8), chain.getAdjustedPosY(0, math.Pi/6, -10)}
-	interpolate2BezierVertices(&path, p1, p2, p3)
-
-	prev = Point{chain.getAdjustedPosX(0, math.Pi/6, -8), chain.getAdjustedPosY(0, math.Pi/6, -10)}
-	curr = chain.joints[0].Right()
-	next = chain.joints[1].Right()
-	interpolateBezierVertices(&path, prev, curr, next)
+	path := b.createPath()
+	b.vertices, b.indices = drawPath(screen, path, b.style, b.vertices, b.indices)
 
-	return &path
+	b.drawEyes(screen)
 }
 
-func (b *Lizard) draw(screen *ebiten.Image) {
+func (b *Lizard) sketch(s *Sketch) {
 	for _, l := range b.limbs {
-		l.draw(screen)
+		l.sketch(s, b.style)
 	}
+	s.body(b.chain, b.style)
+	s.eyes(b.chain.First(), b.style)
+}
 
-	path := b.createPath()
-
-	// Render the filled area
-	b.vertices, b.indices = path.AppendVerticesAndIndicesForFilling(b.vertices[:0], b.indices[:0])
-	for i := range b.vertices {
-		b.vertices[i].SrcX = 1
-		b.vertices[i].SrcY = 1
-		b.vertices[i].ColorR = 0x58 / float32(0xff)
-		b.vertices[i].ColorG = 0x85 / float32(0xff)
-		b.vertices[i].ColorB = 0x7A / float32(0xff)
-		b.vertices[i].ColorA = 1
+func (b *Lizard) visitState(v stateVisitor) {
+	visitChain(v, "body", b.chain)
+	v.float("speed", &b.speed)
+	v.float("gait.phase", &b.gait.phase)
+	for i, l := range b.limbs {
+		l.visitState(prefixed{v, "limb" + strconv.Itoa(i) + "."})
 	}
-	top := &ebiten.DrawTrianglesOptions{}
-	top.AntiAlias = true
-	top.FillRule = ebiten.FillRuleNonZero
-	screen.DrawTriangles(b.vertices, b.indices, outlineSubImage, top)
-
-	// Render the outline
-	sop := &vector.StrokeOptions{}
-	sop.Width = 3
-	sop.LineJoin = vector.LineJoinRound
-	b.vertices, b.indices = path.AppendVerticesAndIndicesForStroke(b.vertices[:0], b.indices[:0], sop)
-	screen.DrawTriangles(b.vertices, b.indices, outlineSubImage, top)
+}
 
-	b.drawEyes(screen)
+// The body first, then the limbs
+func (b *Lizard) chains() []*ik.Chain {
+	chains := []*ik.Chain{b.chain}
+	for _, l := range b.limbs {
+		chains = append(chains, l.chain)
+	}
+	return chains
 }
 
-// Conclrete and detailed implementation of how to draw the lizard's eyes
-// No need to generalize and regard DRY!
 func (b *Lizard) drawEyes(screen *ebiten.Image) {
-	p := b.chain.first()
-	angle := p.angle + 3*math.Pi/5
-	radius := p.radius - 7
-	x := p.pos.x + math.Cos(angle)*(radius)
-	y := p.pos.y + math.Sin(angle)*(radius)
-	vector.DrawFilledCircle(screen,
-		float32(x), float32(y),
-		float32(10),
-		color.White, true)
-
-	angle = p.angle - 3*math.Pi/5
-	radius = p.radius - 7
-	x = p.pos.x + math.Cos(angle)*(radius)
-	y = p.pos.y + math.Sin(angle)*(radius)
-	vector.DrawFilledCircle(screen,
-		float32(x), float32(y),
-		float32(10),
-		color.White, true)
+	drawEyes(screen, b.chain.First(), b.style)
 }
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"image"
 	"image/color"
 	"log"
+	"os"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/mpja69/moving_snakes/ik"
+	"github.com/mpja69/moving_snakes/terrain"
 )
 
 const (
@@ -15,57 +22,350 @@ const (
 )
 
 var (
+	backgroundColor = color.RGBA{44, 51, 60, 255}
 	outlineImage    = ebiten.NewImage(3, 3)
 	outlineSubImage = outlineImage.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
-	fillImage       = ebiten.NewImage(3, 3)
-	fillSubImage    = fillImage.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
 )
 
 func init() {
-	fillImage.Fill(color.RGBA{0x58, 0x85, 0x7A, 255})
 	outlineImage.Fill(color.White)
 }
 
+// The mouse pointer, on the back buffer
+func (g *Game) cursor(int) ik.Point {
+	x, y := ebiten.CursorPosition()
+	return ik.Point{X: float64(x * FACTOR), Y: float64(y * FACTOR)}
+}
+
 // Use the mouse pointer as target
 func (g *Game) Update() error {
-	x, y := ebiten.CursorPosition()
-	tx := float64(x * FACTOR)
-	ty := float64(y * FACTOR)
+	path := g.path
+	if path == nil {
+		path = g.cursor
+	}
+	// ebiten calls Update TPS times per second, the creature moves in fixed steps anyway
+	g.clock.Advance(g.creature, path, g.elapsed(time.Now()))
 
-	g.lizard.update(Point{tx, ty})
+	// Save the pose
+	if g.svgFile != "" && inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		if err := SketchOf(g.creature).SaveSVG(g.svgFile); err != nil {
+			return err
+		}
+		log.Printf("saved %s", g.svgFile)
+	}
+	// Bookmark the state
+	if g.saveFile != "" && inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		if err := TakeSnapshot(g.creature, g.clock.Steps()).Save(g.saveFile); err != nil {
+			return err
+		}
+		log.Printf("saved %s", g.saveFile)
+	}
 
 	return nil
 }
 
+// Seconds since the last update: 1/TPS, or measured when ebiten updates once a frame, (-tps=-1)
+func (g *Game) elapsed(now time.Time) float64 {
+	if tps := ebiten.TPS(); tps > 0 {
+		return 1 / float64(tps)
+	}
+	last := g.lastUpdate
+	g.lastUpdate = now
+	if last.IsZero() {
+		return 0
+	}
+	return now.Sub(last).Seconds()
+}
+
 func (g *Game) Draw(screen *ebiten.Image) {
-	g.backBuffer.Fill(color.RGBA{44, 51, 60, 255})
-	g.lizard.draw(g.backBuffer)
+	g.backBuffer.Fill(backgroundColor)
+	if g.ground != nil {
+		drawTerrain(g.backBuffer, g.ground)
+	}
+	g.clock.Draw(g.creature, func() {
+		g.creature.draw(g.backBuffer)
+	})
 
-	// g.lizard.debugDraw(g.backBuffer)
+	// g.creature.debugDraw(g.backBuffer)
+	g.record()
 	opts := ebiten.DrawImageOptions{}
 	opts.GeoM.Scale(1/FACTOR, 1/FACTOR)
 	screen.DrawImage(g.backBuffer, &opts)
 }
 
+// Save the back buffer, (shrunk to the scale), every so many ticks
+func (g *Game) record() {
+	if g.recorder == nil || g.clock.Steps()-g.recorded < g.recordEvery {
+		return
+	}
+	g.recorded = g.clock.Steps()
+	img := image.NewRGBA(g.backBuffer.Bounds())
+	g.backBuffer.ReadPixels(img.Pix)
+	if err := g.recorder.Add(resize(img, g.recordScale)); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func (g *Game) Layout(_, _ int) (int, int) {
 	return WIDTH, HEIGHT
 }
 
 type Game struct {
-	lizard     *Lizard
-	backBuffer *ebiten.Image
+	creature Creature
+	clock    *Clock
+	path     TargetPath // Where to go instead of the mouse pointer, when set
+	ground   *terrain.Terrain
+	svgFile  string // Where the S key saves the pose
+	saveFile string // Where the B key saves a snapshot
+	// Frames, when recording
+	recorder    *Recorder
+	recordEvery int // Ticks between frames
+	recordScale float64
+	recorded    int // The tick of the last frame
+	backBuffer  *ebiten.Image
+	lastUpdate  time.Time // When synced with the FPS
 }
 
 func main() {
+	headless := flag.Bool("headless", false, "run the simulation without a window and dump joint positions as CSV")
+	ticks := flag.Int("ticks", 600, "number of ticks to simulate in headless mode")
+	wholeBody := flag.Bool("wholebody", false, "solve the spine and the limbs as one skeleton, so the feet pull the body")
+	collide := flag.Bool("collide", false, "keep lizard bodies from passing through themselves, (worlds always keep bodies apart)")
+	roam := flag.Bool("roam", false, "let the creature roam on its own, (steering behaviors), instead of following the mouse")
+	verlet := flag.Bool("verlet", false, "let the body swing with inertia, (Verlet integration), instead of following the head directly")
+	gaitName := flag.String("gait", "", "how the legs are coordinated: trot, walk, lateral, gallop or wave, (default from the creature)")
+	tps := flag.Int("tps", ebiten.DefaultTPS, "ebiten updates per second, or -1 for once a frame, (the simulation steps 60 times per second anyway)")
+	crowd := flag.Int("crowd", 0, "run a world of this many creatures, flocking loosely towards the target")
+	kind := flag.String("kind", "lizard", "which creature to run: lizard, snake or fish")
+	creature := flag.String("creature", "", "JSON creature definition to load instead of the built in lizard")
+	svgFile := flag.String("svg", "", "save the pose as SVG to this file: at the end in headless mode, or when S is pressed")
+	recordDir := flag.String("record", "", "save PNG frames in this directory: painted in software in headless mode, (instead of the CSV), or from the window")
+	gifFile := flag.String("gif", "", "save the frames as an animated GIF to this file, (like -record)")
+	every := flag.Int("every", 2, "ticks between recorded frames, (a GIF leaves out those closer than 1/50 s)")
+	scale := flag.Float64("scale", 0.5, "size of the recorded frames, compared to the back buffer, (0.5 is the window size)")
+	inputFile := flag.String("input", "", "log the target of every tick to this file, to -replay the run exactly")
+	replayFile := flag.String("replay", "", "replay an -input log without a window, (with the flags it was recorded with), and check the joints end up the same")
+	snapshotFile := flag.String("snapshot", "", "start from a snapshot, (of a creature built with the same flags)")
+	saveFile := flag.String("save", "", "save a snapshot to this file, (JSON if it ends in .json, else binary): at the end in headless mode, or when B is pressed")
+	terrainFile := flag.String("terrain", "", "JSON terrain to walk in: obstacles to find the way around, and ground the feet can't be put on")
+	flag.Parse()
+	if *tps <= 0 && *tps != ebiten.SyncWithFPS {
+		log.Fatalf("-tps must be above 0, or %d for once a frame", ebiten.SyncWithFPS)
+	}
+
+	// A replay builds the scene with the flags it was recorded with
+	var replay *InputLog
+	if *replayFile != "" {
+		var err error
+		if replay, err = LoadInputLog(*replayFile); err != nil {
+			log.Fatal(err)
+		}
+		if err := flag.CommandLine.Parse(replay.Args); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	def := LizardDefinition
+	if *creature != "" {
+		loaded, err := LoadDefinition(*creature)
+		if err != nil {
+			log.Fatal(err)
+		}
+		def = *loaded
+	}
+	if *gaitName != "" {
+		def.Gait = *gaitName
+	}
+	var ground *terrain.Terrain
+	if *terrainFile != "" {
+		if *crowd > 0 {
+			log.Fatal("-terrain is for a single creature, not a -crowd")
+		}
+		var err error
+		if ground, err = LoadTerrain(*terrainFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *verlet && def.Body.Verlet == nil {
+		opts := ik.DefaultVerletOptions
+		def.Body.Verlet = &VerletDefinition{Damping: opts.Damping, Stiffness: opts.Stiffness, Iterations: opts.Iterations}
+	}
+	newCreature := func(x, y int) Creature {
+		switch *kind {
+		case "lizard":
+			lizard, err := LizardFromDefinition(&def, x, y)
+			if err != nil {
+				log.Fatal(err)
+			}
+			lizard.SetWholeBody(*wholeBody)
+			lizard.SetSelfCollision(*collide)
+			if ground != nil {
+				lizard.SetTerrain(ground)
+			}
+			return lizard
+		case "snake":
+			return SnakeNew(x, y)
+		case "fish":
+			return FishNew(x, y)
+		}
+		log.Fatalf("unknown creature kind %q", *kind)
+		return nil
+	}
+	// One creature in the middle, or a whole world of them
+	newScene := func(seed int64) Creature {
+		if *crowd <= 0 {
+			return newCreature(WIDTH/2, HEIGHT/2)
+		}
+		w := WorldNew(ik.Point{}, ik.Point{X: WIDTH * FACTOR, Y: HEIGHT * FACTOR})
+		w.Spawn(*crowd, seed, newCreature)
+		return w
+	}
+	// The members of a world roam anyway, so let the world as a whole hang around the middle
+	roamPath := func(c Creature, seed int64) TargetPath {
+		if *crowd > 0 {
+			return WaypointPath([]ik.Point{{X: WIDTH, Y: HEIGHT}}, 1)
+		}
+		return RoamPath(c, seed)
+	}
+	// Find the way around the obstacles, to wherever the path goes
+	aroundObstacles := func(c Creature, path TargetPath) TargetPath {
+		if ground == nil {
+			return path
+		}
+		return PathfindingPath(c, ground, path)
+	}
+
+	// Pick up where a snapshot left off
+	resume := func(c Creature) int {
+		if *snapshotFile == "" {
+			return 0
+		}
+		snap, err := LoadSnapshot(*snapshotFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := snap.Restore(c); err != nil {
+			log.Fatalf("%s: %v", *snapshotFile, err)
+		}
+		return snap.Tick
+	}
+
+	if replay != nil {
+		c := newScene(replay.Seed)
+		if start := resume(c); start != replay.Start {
+			log.Fatalf("%s: recorded from tick %d, the snapshot is at %d", *replayFile, replay.Start, start)
+		}
+		if err := replay.Replay(c); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("replayed %d ticks, checksum %016x\n", len(replay.Targets), Checksum(c))
+		return
+	}
+	// Log the targets the creature gets, (wherever they come from), with the flags that shape the scene
+	var inputs *InputRecorder
+	logInputs := func(c Creature, seed int64, start int, path TargetPath) TargetPath {
+		if *inputFile == "" {
+			return path
+		}
+		args := []string{}
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "input", "replay", "svg", "save", "record", "gif", "every", "scale", "headless", "ticks":
+			default:
+				args = append(args, "-"+f.Name+"="+f.Value.String())
+			}
+		})
+		var err error
+		if inputs, err = InputRecorderNew(*inputFile, args, seed, start, c); err != nil {
+			log.Fatal(err)
+		}
+		return inputs.Path(path)
+	}
+
+	var recorder *Recorder
+	if *recordDir != "" || *gifFile != "" {
+		if *every < 1 || *scale <= 0 || *scale > 1 {
+			log.Fatal("-every must be at least 1, and -scale between 0 and 1")
+		}
+		var err error
+		if recorder, err = RecorderNew(*recordDir, *gifFile, float64(*every)*TickDuration); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *headless {
+		c := newScene(1)
+		path := CirclePath(ik.Point{X: WIDTH, Y: HEIGHT}, HEIGHT/2, 1200)
+		if *roam {
+			path = roamPath(c, 1)
+		}
+		start := resume(c)
+		sim := SimulatorNew(c, logInputs(c, 1, start, aroundObstacles(c, path)))
+		sim.tick = start
+		if recorder != nil {
+			if err := sim.Record(*ticks, *every, *scale, recorder); err != nil {
+				log.Fatal(err)
+			}
+		} else if err := sim.Run(*ticks, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		if *svgFile != "" {
+			if err := SketchOf(c).SaveSVG(*svgFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *saveFile != "" {
+			if err := TakeSnapshot(c, sim.Tick()).Save(*saveFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if inputs != nil {
+			if err := inputs.Close(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		return
+	}
+
 	ebiten.SetWindowSize(WIDTH, HEIGHT)
 	ebiten.SetWindowTitle("Inverse kinematics!")
+	seed := time.Now().UnixNano()
 	g := Game{
-		lizard: LizardNew(WIDTH/2, HEIGHT/2),
+		creature: newScene(seed),
+		clock:    ClockNew(TickDuration),
+		ground:   ground,
+		svgFile:  *svgFile,
+		saveFile: *saveFile,
+
+		recorder:    recorder,
+		recordEvery: *every,
+		recordScale: *scale,
 	}
+	g.clock.steps = resume(g.creature)
+	if *roam {
+		g.path = roamPath(g.creature, seed)
+	}
+	if g.path == nil && (ground != nil || *inputFile != "") {
+		g.path = g.cursor
+	}
+	if g.path != nil {
+		g.path = logInputs(g.creature, seed, g.clock.steps, aroundObstacles(g.creature, g.path))
+	}
+	ebiten.SetTPS(*tps)
 	// Create a bigger backbuffer
 	g.backBuffer = ebiten.NewImage(WIDTH*FACTOR, HEIGHT*FACTOR)
 
 	if err := ebiten.RunGame(&g); err != nil {
 		log.Fatal(err)
 	}
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if inputs != nil {
+		if err := inputs.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
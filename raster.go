@@ -0,0 +1,272 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const (
+	rasterSubsamples = 4   // Sample rows per pixel row, for the anti aliasing
+	rasterFlatness   = 1.5 // Pixels per line segment, when a curve is flattened
+	rasterRoundSides = 24  // Of the polygons for round joins and caps
+	rasterRoundTurn  = 0.1 // Radians. Smaller turns are joined well enough by the segments' corners
+)
+
+// Paint the sketch into an image, in software, (without ebiten). The sketch is scaled, e.g. 0.5 for the window
+// size of a back buffer sketch. Fills use the non zero rule, and strokes have round joins
+func (s *Sketch) Rasterize(scale float64) *image.RGBA {
+	w, h := int(math.Ceil(s.Width*scale)), int(math.Ceil(s.Height*scale))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	if s.Background.A > 0 {
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = s.Background.R, s.Background.G, s.Background.B, s.Background.A
+		}
+	}
+	for _, shape := range s.Shapes {
+		subpaths := shape.Outline.flatten(scale)
+		if shape.Fill != nil {
+			fillPolygons(img, subpaths, *shape.Fill)
+		}
+		if shape.Stroke != nil && shape.Width > 0 {
+			fillPolygons(img, strokePolygons(subpaths, shape.Width*scale/2), *shape.Stroke)
+		}
+	}
+	return img
+}
+
+// The outline as polylines, one per sub path, scaled. A closed sub path ends where it started
+func (o *Outline) flatten(scale float64) [][]ik.Point {
+	var subpaths [][]ik.Point
+	var curr []ik.Point
+	at := ik.Point{}
+	for _, seg := range o.Segments {
+		pts := make([]ik.Point, len(seg.Points))
+		for i, p := range seg.Points {
+			pts[i] = ik.Point{X: p.X * scale, Y: p.Y * scale}
+		}
+		switch seg.Op {
+		case 'M':
+			if len(curr) > 0 {
+				subpaths = append(subpaths, curr)
+			}
+			curr = []ik.Point{pts[0]}
+		case 'L':
+			curr = append(curr, pts[0])
+		case 'Q':
+			curr = appendCurve(curr, []ik.Point{at, pts[0], pts[1]})
+		case 'C':
+			curr = appendCurve(curr, []ik.Point{at, pts[0], pts[1], pts[2]})
+		case 'Z':
+			if len(curr) > 0 {
+				curr = append(curr, curr[0])
+				subpaths = append(subpaths, curr)
+				curr = []ik.Point{curr[0]}
+			}
+		}
+		if len(curr) > 0 {
+			at = curr[len(curr)-1]
+		}
+	}
+	if len(curr) > 1 {
+		subpaths = append(subpaths, curr)
+	}
+	return subpaths
+}
+
+// Append the Bezier curve, (quadratic or cubic, the start included in ctrl), as line segments
+func appendCurve(poly []ik.Point, ctrl []ik.Point) []ik.Point {
+	length := 0.0
+	for i := 1; i < len(ctrl); i++ {
+		length += ik.Distance(ctrl[i-1], ctrl[i])
+	}
+	n := max(1, min(256, int(math.Ceil(length/rasterFlatness))))
+	for k := 1; k <= n; k++ {
+		poly = append(poly, bezierAt(ctrl, float64(k)/float64(n)))
+	}
+	return poly
+}
+
+// de Casteljau
+func bezierAt(ctrl []ik.Point, t float64) ik.Point {
+	var pts [4]ik.Point
+	n := copy(pts[:], ctrl)
+	for ; n > 1; n-- {
+		for i := 0; i < n-1; i++ {
+			pts[i] = ik.Point{X: pts[i].X + (pts[i+1].X-pts[i].X)*t, Y: pts[i].Y + (pts[i+1].Y-pts[i].Y)*t}
+		}
+	}
+	return pts[0]
+}
+
+// A wide line along the polylines, as polygons that overlap: a rectangle along every segment, and a circle at
+// the ends and where the line turns. All turn the same way, so filling them with the non zero rule paints their
+// union
+func strokePolygons(polylines [][]ik.Point, r float64) [][]ik.Point {
+	var polys [][]ik.Point
+	for _, line := range polylines {
+		for i, p := range line {
+			if i == 0 || i == len(line)-1 || turn(line[i-1], p, line[i+1]) > rasterRoundTurn {
+				circle := make([]ik.Point, rasterRoundSides)
+				for k := range circle {
+					a := 2 * math.Pi * float64(k) / rasterRoundSides
+					circle[k] = ik.Point{X: p.X + r*math.Cos(a), Y: p.Y + r*math.Sin(a)}
+				}
+				polys = append(polys, circle)
+			}
+			if i == 0 {
+				continue
+			}
+			a := line[i-1]
+			d := p.Sub(a)
+			if d.Mag() == 0 {
+				continue
+			}
+			n := ik.Point{X: -d.Y, Y: d.X}.SetMag(r)
+			polys = append(polys, []ik.Point{a.Add(n), p.Add(n), p.Sub(n), a.Sub(n)})
+		}
+	}
+	for _, poly := range polys {
+		if signedArea(poly) < 0 {
+			for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+				poly[i], poly[j] = poly[j], poly[i]
+			}
+		}
+	}
+	return polys
+}
+
+// How much the line turns at b, in radians
+func turn(a, b, c ik.Point) float64 {
+	d := math.Abs(a.Angle(b) - b.Angle(c))
+	return math.Min(d, 2*math.Pi-d)
+}
+
+func signedArea(poly []ik.Point) float64 {
+	area := 0.0
+	for i, p := range poly {
+		q := poly[(i+1)%len(poly)]
+		area += p.X*q.Y - q.X*p.Y
+	}
+	return area / 2
+}
+
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int // +1 downwards, -1 upwards
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// Fill the polygons, (each implicitly closed), with the non zero winding rule. Each pixel is covered by the
+// share of its sample rows, and the part of each row, inside
+func fillPolygons(img *image.RGBA, polys [][]ik.Point, c color.RGBA) {
+	var edges []edge
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, poly := range polys {
+		for i, p := range poly {
+			q := poly[(i+1)%len(poly)]
+			if p.Y == q.Y {
+				continue
+			}
+			e := edge{p.X, p.Y, q.X, q.Y, 1}
+			if p.Y > q.Y {
+				e = edge{q.X, q.Y, p.X, p.Y, -1}
+			}
+			edges = append(edges, e)
+			minY, maxY = math.Min(minY, e.y0), math.Max(maxY, e.y1)
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].y0 < edges[j].y0 })
+	b := img.Bounds()
+	y0 := max(b.Min.Y, int(math.Floor(minY)))
+	y1 := min(b.Max.Y, int(math.Ceil(maxY)))
+	w := b.Dx()
+	cover := make([]float64, w+1)
+	var crossings []crossing
+	var active []edge
+	next := 0
+	for y := y0; y < y1; y++ {
+		clear(cover)
+		touched := false
+		for s := 0; s < rasterSubsamples; s++ {
+			sy := float64(y) + (float64(s)+0.5)/rasterSubsamples
+			// The edges the sample row crosses: add the ones starting above it, drop the ones ending
+			for ; next < len(edges) && edges[next].y0 <= sy; next++ {
+				active = append(active, edges[next])
+			}
+			crossings = crossings[:0]
+			kept := active[:0]
+			for _, e := range active {
+				if sy >= e.y1 {
+					continue
+				}
+				kept = append(kept, e)
+				x := e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+				crossings = append(crossings, crossing{x, e.dir})
+			}
+			active = kept
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding, start := 0, 0.0
+			for _, cr := range crossings {
+				prev := winding
+				winding += cr.dir
+				switch {
+				case prev == 0 && winding != 0:
+					start = cr.x
+				case prev != 0 && winding == 0:
+					addSpan(cover, start-float64(b.Min.X), cr.x-float64(b.Min.X), 1.0/rasterSubsamples)
+					touched = true
+				}
+			}
+		}
+		if touched {
+			blendRow(img, y, cover, c)
+		}
+	}
+}
+
+// Add the span from xa to xb to the row's coverage, with partly covered pixels at the ends
+func addSpan(cover []float64, xa, xb, weight float64) {
+	w := float64(len(cover) - 1)
+	xa, xb = math.Max(0, xa), math.Min(w, xb)
+	if xb <= xa {
+		return
+	}
+	ia, ib := int(xa), int(xb)
+	if ia == ib {
+		cover[ia] += (xb - xa) * weight
+		return
+	}
+	cover[ia] += (float64(ia+1) - xa) * weight
+	for i := ia + 1; i < ib; i++ {
+		cover[i] += weight
+	}
+	cover[ib] += (xb - float64(ib)) * weight
+}
+
+// Paint the color over the row, as much as each pixel is covered
+func blendRow(img *image.RGBA, y int, cover []float64, c color.RGBA) {
+	b := img.Bounds()
+	for x := 0; x < b.Dx(); x++ {
+		a := math.Min(1, cover[x]) * float64(c.A) / 0xff
+		if a <= 0 {
+			continue
+		}
+		i := img.PixOffset(b.Min.X+x, y)
+		px := img.Pix[i : i+4 : i+4]
+		px[0] = uint8(float64(c.R)*a + float64(px[0])*(1-a) + 0.5)
+		px[1] = uint8(float64(c.G)*a + float64(px[1])*(1-a) + 0.5)
+		px[2] = uint8(float64(c.B)*a + float64(px[2])*(1-a) + 0.5)
+		px[3] = uint8(0xff*a + float64(px[3])*(1-a) + 0.5)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	gifColors = 256
+	// The GIF is encoded at the end, so its frames are kept in memory until then: at most this many bytes, (a
+	// byte a pixel). Later frames are left out of it, (not the PNG files)
+	gifMaxBytes = 512 << 20
+	// Hundredths of a second: viewers show a frame with a shorter delay for much longer, (often 10)
+	gifMinDelay = 2
+)
+
+// Saves frames as numbered PNG files in a directory, and/or as an animated GIF. The GIF's palette is chosen
+// from the first frame, (the background and the creatures' colors are there from the start)
+type Recorder struct {
+	dir      string // No PNG files when empty
+	gifPath  string // No GIF when empty
+	interval float64
+	frames   int
+	anim     gif.GIF
+	palette  color.Palette
+	indexOf  map[color.RGBA]uint8
+	shown    int // Hundredths of seconds of the GIF so far
+	bytes    int // Of the GIF's frames
+	dropped  int // Frames left out of the GIF
+}
+
+// interval is the seconds between frames
+func RecorderNew(dir, gifPath string, interval float64) (*Recorder, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &Recorder{dir: dir, gifPath: gifPath, interval: interval}, nil
+}
+
+func (r *Recorder) Frames() int {
+	return r.frames
+}
+
+func (r *Recorder) Add(img *image.RGBA) error {
+	if r.dir != "" {
+		if err := savePNG(filepath.Join(r.dir, fmt.Sprintf("frame-%05d.png", r.frames)), img); err != nil {
+			return err
+		}
+	}
+	// GIF delays are in hundredths: round the time so far, so the clip doesn't drift. A frame that would be
+	// shown for less than the least delay is left out of the GIF, and its time goes to the next one
+	end := int(math.Round(float64(r.frames+1) * r.interval * 100))
+	switch {
+	case r.gifPath == "" || end-r.shown < gifMinDelay:
+	case r.bytes+len(img.Pix)/4 > gifMaxBytes:
+		r.dropped++
+	default:
+		r.bytes += len(img.Pix) / 4
+		if r.palette == nil {
+			r.palette = quantize(img, gifColors)
+			r.indexOf = map[color.RGBA]uint8{}
+		}
+		r.anim.Image = append(r.anim.Image, r.paletted(img))
+		r.anim.Delay = append(r.anim.Delay, end-r.shown)
+		r.shown = end
+	}
+	r.frames++
+	return nil
+}
+
+// Write the GIF, (the PNG files are written as they come)
+func (r *Recorder) Close() error {
+	if r.gifPath == "" || len(r.anim.Image) == 0 {
+		return nil
+	}
+	f, err := os.Create(r.gifPath)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &r.anim); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if r.dropped > 0 {
+		return fmt.Errorf("%s has the first %d frames only, %d more didn't fit in memory: record fewer (-every) or smaller (-scale) frames, or PNG files (-record)", r.gifPath, len(r.anim.Image), r.dropped)
+	}
+	return nil
+}
+
+// The image in the palette's nearest colors, (remembered, since the same few colors come back every frame)
+func (r *Recorder) paletted(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	p := image.NewPaletted(b, r.palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			i, ok := r.indexOf[c]
+			if !ok {
+				i = uint8(r.palette.Index(c))
+				r.indexOf[c] = i
+			}
+			p.SetColorIndex(x, y, i)
+		}
+	}
+	return p
+}
+
+func savePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// At most n colors for the image, by median cut: split the box of colors with the widest channel at its
+// median, until there are n boxes, and take the average of each
+func quantize(img *image.RGBA, n int) color.Palette {
+	// Count each color once, weighted by how often it's used
+	counts := map[color.RGBA]int{}
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			counts[img.RGBAAt(x, y)]++
+		}
+	}
+	type weighted struct {
+		c color.RGBA
+		n int
+	}
+	all := make([]weighted, 0, len(counts))
+	for c, k := range counts {
+		all = append(all, weighted{c, k})
+	}
+	sort.Slice(all, func(i, j int) bool { return rgbaKey(all[i].c) < rgbaKey(all[j].c) })
+
+	channel := func(c color.RGBA, ch int) uint8 {
+		return [4]uint8{c.R, c.G, c.B, c.A}[ch]
+	}
+	// The widest channel of a box, and how wide
+	widest := func(box []weighted) (int, int) {
+		best, bestRange := 0, -1
+		for ch := 0; ch < 4; ch++ {
+			lo, hi := 255, 0
+			for _, w := range box {
+				v := int(channel(w.c, ch))
+				lo, hi = min(lo, v), max(hi, v)
+			}
+			if hi-lo > bestRange {
+				best, bestRange = ch, hi-lo
+			}
+		}
+		return best, bestRange
+	}
+
+	boxes := [][]weighted{all}
+	for len(boxes) < n {
+		// Split the box with the widest range
+		split, ch, width := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if c, w := widest(box); w > width {
+				split, ch, width = i, c, w
+			}
+		}
+		if split < 0 {
+			break
+		}
+		box := boxes[split]
+		sort.Slice(box, func(i, j int) bool { return channel(box[i].c, ch) < channel(box[j].c, ch) })
+		total := 0
+		for _, w := range box {
+			total += w.n
+		}
+		// The weighted median, keeping both halves non empty
+		m, acc := 1, box[0].n
+		for m < len(box)-1 && acc*2 < total {
+			acc += box[m].n
+			m++
+		}
+		boxes[split] = box[:m]
+		boxes = append(boxes, box[m:])
+	}
+
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var r, g, b, a, total float64
+		for _, w := range box {
+			k := float64(w.n)
+			r, g, b, a = r+float64(w.c.R)*k, g+float64(w.c.G)*k, b+float64(w.c.B)*k, a+float64(w.c.A)*k
+			total += k
+		}
+		palette = append(palette, color.RGBA{uint8(r/total + 0.5), uint8(g/total + 0.5), uint8(b/total + 0.5), uint8(a/total + 0.5)})
+	}
+	return palette
+}
+
+func rgbaKey(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// Step the simulation n ticks, like Run, but paint a frame every so many ticks in software, (scaled from the
+// back buffer), instead of writing the joints
+func (s *Simulator) Record(n, every int, scale float64, rec *Recorder) error {
+	for i := 0; i < n; i++ {
+		s.Step()
+		if i%every == 0 {
+			if err := rec.Add(SketchOf(s.creature).Rasterize(scale)); err != nil {
+				return err
+			}
+		}
+	}
+	return rec.Close()
+}
+
+// Scale the image down, (the size rounded up, like Rasterize), averaging the pixels each new one covers
+func resize(img *image.RGBA, scale float64) *image.RGBA {
+	if scale >= 1 {
+		return img
+	}
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(float64(b.Dx())*scale)), int(math.Ceil(float64(b.Dy())*scale))))
+	// The source pixels from the one under the new pixel's top left corner, to the one under the next
+	span := func(i, size int) (int, int) {
+		lo := min(size-1, int(float64(i)/scale))
+		hi := min(size, int(float64(i+1)/scale))
+		return lo, max(lo+1, hi)
+	}
+	for y := 0; y < out.Rect.Dy(); y++ {
+		y0, y1 := span(y, b.Dy())
+		for x := 0; x < out.Rect.Dx(); x++ {
+			x0, x1 := span(x, b.Dx())
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				i := img.PixOffset(b.Min.X+x0, b.Min.Y+sy)
+				for sx := 0; sx < x1-x0; sx++ {
+					for ch := 0; ch < 4; ch++ {
+						sum[ch] += int(img.Pix[i+sx*4+ch])
+					}
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			o := out.PixOffset(x, y)
+			for ch := 0; ch < 4; ch++ {
+				out.Pix[o+ch] = uint8((sum[ch] + n/2) / n)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestRasterize(t *testing.T) {
+	lizard := LizardNew(600, 400)
+	img := SketchOf(lizard).Rasterize(0.5)
+	if b := img.Bounds(); b.Dx() != WIDTH || b.Dy() != HEIGHT {
+		t.Fatalf("ERR: actual: %v,  expected: %vx%v", b, WIDTH, HEIGHT)
+	}
+	// The middle of the body, (not under a limb or an eye), and far from it
+	mid := lizard.chain.Joints[5].Pos
+	if c := img.RGBAAt(int(mid.X/2), int(mid.Y/2)); c != lizard.style.Fill {
+		t.Errorf("ERR: actual: %v,  expected: %v", c, lizard.style.Fill)
+	}
+	if c := img.RGBAAt(WIDTH-1, HEIGHT-1); c != backgroundColor {
+		t.Errorf("ERR: actual: %v,  expected: %v", c, backgroundColor)
+	}
+	// The white outline, just inside the side of the head
+	head := lizard.chain.First()
+	edge := head.AdjustedPos(math.Pi/2, -1)
+	if c := img.RGBAAt(int(edge.X/2), int(edge.Y/2)); c.R < 160 {
+		t.Errorf("ERR: actual: %v,  expected: close to white", c)
+	}
+}
+
+func TestRecordHeadless(t *testing.T) {
+	dir := t.TempDir()
+	gifPath := filepath.Join(dir, "clip.gif")
+	rec, err := RecorderNew(filepath.Join(dir, "frames"), gifPath, 10*TickDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := SimulatorNew(LizardNew(600, 400), WaypointPath([]ik.Point{{X: 1800, Y: 400}}, 1))
+	if err := sim.Record(60, 10, 0.25, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	frames, _ := filepath.Glob(filepath.Join(dir, "frames", "*.png"))
+	if len(frames) != 6 {
+		t.Fatalf("ERR: actual: %v,  expected: %v", len(frames), 6)
+	}
+	f, err := os.Open(frames[5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != WIDTH/2 || b.Dy() != HEIGHT/2 {
+		t.Errorf("ERR: actual: %v,  expected: %vx%v", b, WIDTH/2, HEIGHT/2)
+	}
+
+	g, err := os.Open(gifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	anim, err := gif.DecodeAll(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, d := range anim.Delay {
+		total += d
+	}
+	if len(anim.Image) != 6 || total != 100 {
+		t.Errorf("ERR: actual: %v frames, %v hundredths,  expected: %v, %v", len(anim.Image), total, 6, 100)
+	}
+	// The background, and the lizard's colors, are in the palette exactly
+	palette := anim.Image[0].Palette
+	for _, c := range []color.RGBA{backgroundColor, LizardNew(0, 0).style.Fill} {
+		if palette.Convert(c) != c {
+			t.Errorf("ERR: actual: %v,  expected: %v", palette.Convert(c), c)
+		}
+	}
+}
+
+// A frame every tick is more than a GIF can show: every other one is left out, so it plays in real time
+func TestRecordGIFMinDelay(t *testing.T) {
+	gifPath := filepath.Join(t.TempDir(), "clip.gif")
+	rec, err := RecorderNew("", gifPath, TickDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for i := 0; i < TickRate; i++ {
+		if err := rec.Add(img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := os.Open(gifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	anim, err := gif.DecodeAll(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for i, d := range anim.Delay {
+		if d < gifMinDelay {
+			t.Errorf("ERR: frame %d: actual: %v,  expected: at least %v", i, d, gifMinDelay)
+		}
+		total += d
+	}
+	if total != 100 || rec.Frames() != TickRate {
+		t.Errorf("ERR: actual: %v hundredths, %v frames,  expected: %v, %v", total, rec.Frames(), 100, TickRate)
+	}
+}
+
+func TestResize(t *testing.T) {
+	// Black and white stripes, a pixel wide
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x += 2 {
+			img.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+			img.SetRGBA(x+1, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	tt := []struct {
+		scale float64
+		w, h  int
+	}{
+		{0.5, 200, 150},
+		{0.75, 300, 225},
+		{0.3, 120, 90},
+		{1, 400, 300},
+	}
+	for _, tc := range tt {
+		out := resize(img, tc.scale)
+		if b := out.Bounds(); b.Dx() != tc.w || b.Dy() != tc.h {
+			t.Errorf("ERR: actual: %v,  expected: %vx%v (scale %v)", b, tc.w, tc.h, tc.scale)
+		}
+	}
+	// Two stripes make a gray
+	if c := resize(img, 0.5).RGBAAt(50, 50); c.R < 127 || c.R > 128 || c.A != 255 {
+		t.Errorf("ERR: actual: %v,  expected: gray", c)
+	}
+}
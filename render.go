@@ -0,0 +1,143 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func drawJointCircle(screen *ebiten.Image, j *ik.Joint) {
+	vector.StrokeCircle(screen,
+		float32(j.Pos.X), float32(j.Pos.Y),
+		float32(j.Radius), 2,
+		color.RGBA{255, 255, 255, 255}, true)
+
+}
+
+// Where paths are built: a *vector.Path to draw, or an Outline to export
+type pathBuilder interface {
+	MoveTo(x, y float32)
+	LineTo(x, y float32)
+	QuadTo(x1, y1, x2, y2 float32)
+	CubicTo(x1, y1, x2, y2, x3, y3 float32)
+	Close()
+}
+
+func chainPath(chain *ik.Chain) *vector.Path {
+	path := vector.Path{}
+	appendChainPath(&path, chain)
+	return &path
+}
+
+// The outline of the body around the chain: right side from the head, around the tail, left side back to the head
+func appendChainPath(path pathBuilder, chain *ik.Chain) {
+	// Create the path clockwise around the whole body
+	// Move to start point: First point
+	path.MoveTo(float32(chain.First().Right().X), float32(chain.First().Right().Y))
+
+	// Draw the right side. p1 only used for getting control points, and move through p2 an p3. Take 2 steps in the loop
+	for i := 0; i < len(chain.Joints)-2; i += 1 {
+		prev := chain.Joints[i].Right()
+		curr := chain.Joints[i+1].Right()
+		next := chain.Joints[i+2].Right()
+		interpolateBezierVertices(path, prev, curr, next)
+	}
+
+	// Draw the tail
+	tail := len(chain.Joints) - 1
+	prev := chain.Joints[tail-1].Right()
+	curr := chain.Joints[tail].Right()
+	next := ik.Point{X: chain.AdjustedPosX(tail, math.Pi, 20), Y: chain.AdjustedPosY(tail, math.Pi, 20)}
+	interpolateBezierVertices(path, prev, curr, next)
+
+	prev = chain.Joints[tail].Right()
+	curr = ik.Point{X: chain.AdjustedPosX(tail, math.Pi, 20), Y: chain.AdjustedPosY(tail, math.Pi, 20)}
+	next = chain.Joints[tail].Left()
+	interpolateBezierVertices(path, prev, curr, next)
+
+	prev = ik.Point{X: chain.AdjustedPosX(tail, math.Pi, 20), Y: chain.AdjustedPosY(tail, math.Pi, 20)}
+	curr = chain.Joints[tail].Left()
+	next = chain.Joints[tail-1].Left()
+	interpolateBezierVertices(path, prev, curr, next)
+
+	// Draw the left side
+	for i := len(chain.Joints) - 1; i > 1; i -= 1 {
+		prev := chain.Joints[i].Left()
+		curr := chain.Joints[i-1].Left()
+		next := chain.Joints[i-2].Left()
+		interpolateBezierVertices(path, prev, curr, next)
+	}
+
+	// Draw the head
+	prev = chain.Joints[1].Left()
+	curr = chain.Joints[0].Left()
+	next = ik.Point{X: chain.AdjustedPosX(0, -math.Pi/6, -8), Y: chain.AdjustedPosY(0, -math.Pi/6, -10)}
+	interpolateBezierVertices(path, prev, curr, next)
+
+	// Top of the head (completes the loop)
+	p1 := ik.Point{X: chain.AdjustedPosX(0, -math.Pi/6, -8), Y: chain.AdjustedPosY(0, -math.Pi/6, -10)}
+	p2 := ik.Point{X: chain.AdjustedPosX(0, 0, -6), Y: chain.AdjustedPosY(0, 0, -4)}
+	p3 := ik.Point{X: chain.AdjustedPosX(0, math.Pi/6, -8), Y: chain.AdjustedPosY(0, math.Pi/6, -10)}
+	interpolate2BezierVertices(path, p1, p2, p3)
+
+	prev = ik.Point{X: chain.AdjustedPosX(0, math.Pi/6, -8), Y: chain.AdjustedPosY(0, math.Pi/6, -10)}
+	curr = chain.Joints[0].Right()
+	next = chain.Joints[1].Right()
+	interpolateBezierVertices(path, prev, curr, next)
+}
+
+// Fill the path and stroke its outline. Returns the vertex and index buffers, for reuse
+func drawPath(screen *ebiten.Image, path *vector.Path, style Style, vertices []ebiten.Vertex, indices []uint16) ([]ebiten.Vertex, []uint16) {
+	// Render the filled area
+	vertices, indices = path.AppendVerticesAndIndicesForFilling(vertices[:0], indices[:0])
+	colorVertices(vertices, style.Fill)
+	top := &ebiten.DrawTrianglesOptions{}
+	top.AntiAlias = true
+	top.FillRule = ebiten.FillRuleNonZero
+	screen.DrawTriangles(vertices, indices, outlineSubImage, top)
+
+	// Render the outline
+	sop := &vector.StrokeOptions{}
+	sop.Width = float32(style.OutlineWidth)
+	sop.LineJoin = vector.LineJoinRound
+	vertices, indices = path.AppendVerticesAndIndicesForStroke(vertices[:0], indices[:0], sop)
+	colorVertices(vertices, style.Outline)
+	screen.DrawTriangles(vertices, indices, outlineSubImage, top)
+	return vertices, indices
+}
+
+// Tint the vertices, (drawn from the white outlineSubImage)
+func colorVertices(vertices []ebiten.Vertex, c color.RGBA) {
+	for i := range vertices {
+		vertices[i].SrcX = 1
+		vertices[i].SrcY = 1
+		vertices[i].ColorR = float32(c.R) / 0xff
+		vertices[i].ColorG = float32(c.G) / 0xff
+		vertices[i].ColorB = float32(c.B) / 0xff
+		vertices[i].ColorA = float32(c.A) / 0xff
+	}
+}
+
+// Two filled dots in the outline color, on either side of the head, (of any creature)
+func drawEyes(screen *ebiten.Image, p *ik.Joint, style Style) {
+	for _, eye := range eyes(p) {
+		vector.DrawFilledCircle(screen,
+			float32(eye.X), float32(eye.Y),
+			float32(style.EyeRadius),
+			style.Outline, true)
+	}
+}
+
+// Where the eyes sit on the head, right then left
+func eyes(p *ik.Joint) [2]ik.Point {
+	angle := p.Angle + 3*math.Pi/5
+	radius := p.Radius - 7
+	right := ik.Point{X: p.Pos.X + math.Cos(angle)*(radius), Y: p.Pos.Y + math.Sin(angle)*(radius)}
+
+	angle = p.Angle - 3*math.Pi/5
+	left := ik.Point{X: p.Pos.X + math.Cos(angle)*(radius), Y: p.Pos.Y + math.Sin(angle)*(radius)}
+	return [2]ik.Point{right, left}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// go test -run TestGolden -update, to draw the golden images again, (after a change to how creatures look)
+var update = flag.Bool("update", false, "regenerate the golden images in testdata/golden")
+
+// go test -run TestGolden -update-poses, to simulate the poses again, (after a change to a creature's state)
+var updatePoses = flag.Bool("update-poses", false, "regenerate the pose snapshots in testdata/poses")
+
+const (
+	goldenScale     = 0.5  // Of the back buffer, (the window size)
+	goldenDistance  = 40   // Colors further apart than this, (out of about 765), are seen as different
+	goldenTolerance = 0.01 // Share of the drawn pixels that may differ, (so a small change to the eyes counts)
+)
+
+// Canonical poses, (see pose), drawn offscreen by the software rasterizer, (Sketch.Rasterize), not by ebiten. What's guarded
+// is the geometry the screen shares with it: the outlines of appendChainPath, (under Lizard.createPath), of
+// Limb.appendPath, (under Limb.createPath), and where eyes puts the eyes. How ebiten fills and strokes them,
+// (drawPath, Limb.draw, and the circles of drawEyes), needs a window and a GPU, and isn't covered
+func TestGolden(t *testing.T) {
+	walking := LizardNew(600, 400)
+	pose(t, "walking", walking, ik.Point{X: 1400, Y: 900}, 90)
+	turning := LizardNew(1200, 800)
+	pose(t, "turning", turning, ik.Point{X: 700, Y: 1300}, 120)
+	def, err := LoadDefinition("creatures/spider.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spider, err := LizardFromDefinition(def, 1200, 800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pose(t, "spider", spider, ik.Point{X: 1800, Y: 500}, 90)
+
+	tt := []struct {
+		name   string
+		sketch func(s *Sketch)
+	}{
+		{"body", func(s *Sketch) { s.body(turning.chain, turning.style) }},
+		{"limbs", func(s *Sketch) {
+			for _, l := range walking.limbs {
+				l.sketch(s, walking.style)
+			}
+		}},
+		{"eyes", func(s *Sketch) { s.eyes(turning.chain.First(), turning.style) }},
+		{"lizard", walking.sketch},
+		{"spider", spider.sketch},
+	}
+	for _, tc := range tt {
+		s := SketchNew(WIDTH*FACTOR, HEIGHT*FACTOR, backgroundColor)
+		tc.sketch(s)
+		checkGolden(t, tc.name, s.Rasterize(goldenScale))
+	}
+}
+
+// Put the creature in the state of testdata/poses/<name>.json, so the images only change when the drawing does,
+// (not when the solvers or the gait do). With -update-poses, walk it towards the target for that many ticks first,
+// and save that
+func pose(t *testing.T, name string, c Creature, target ik.Point, ticks int) {
+	t.Helper()
+	path := filepath.Join("testdata", "poses", name+".json")
+	if *updatePoses {
+		step(SimulatorNew(c, WaypointPath([]ik.Point{target}, 1)), ticks)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := TakeSnapshot(c, ticks).Save(path); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	snap, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("%v, (run with -update-poses to create it)", err)
+	}
+	if err := snap.Restore(c); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+}
+
+// Compare with testdata/golden/<name>.png, or write it with -update. A failing image is saved in the temp dir, to look at
+func checkGolden(t *testing.T, name string, img *image.RGBA) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name+".png")
+	if *update {
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := readPNG(path)
+	if err != nil {
+		t.Fatalf("%v, (run with -update to create it)", err)
+	}
+	if golden.Bounds() != img.Bounds() {
+		t.Fatalf("ERR: actual: %v,  expected: %v (%s)", img.Bounds(), golden.Bounds(), name)
+	}
+	differ, drawn := 0, 1
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			want := color.RGBAModel.Convert(golden.At(x, y)).(color.RGBA)
+			if colorDistance(want, backgroundColor) > goldenDistance {
+				drawn++
+			}
+			if colorDistance(img.RGBAAt(x, y), want) > goldenDistance {
+				differ++
+			}
+		}
+	}
+	if share := float64(differ) / float64(drawn); share > goldenTolerance {
+		failed := filepath.Join(os.TempDir(), "moving_snakes_"+name+".png")
+		writePNG(failed, img)
+		t.Errorf("ERR: actual: %.2f%% of the drawn pixels differ,  expected: at most %.2f%% (%s, see %s)", 100*share, 100*goldenTolerance, name, failed)
+	}
+}
+
+// How different two colors look, (the "redmean" weighting of the channels). 0 to about 765
+func colorDistance(a, b color.RGBA) float64 {
+	mean := (float64(a.R) + float64(b.R)) / 2
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return math.Sqrt((2+mean/256)*dr*dr + 4*dg*dg + (2+(255-mean)/256)*db*db)
+}
+
+func TestColorDistance(t *testing.T) {
+	white, black := color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255}
+	if d := colorDistance(white, black); math.Abs(d-765) > 1 {
+		t.Errorf("ERR: actual: %v,  expected: %v", d, 765)
+	}
+	// An anti aliased edge, a step off, is close
+	if d := colorDistance(backgroundColor, color.RGBA{backgroundColor.R + 1, backgroundColor.G + 2, backgroundColor.B, 255}); d > goldenDistance {
+		t.Errorf("ERR: actual: %v,  expected: below %v", d, goldenDistance)
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const (
+	TickRate     = 60 // Simulation steps per second, whatever ebiten's TPS
+	TickDuration = 1.0 / TickRate
+)
+
+// A scripted target: where the creature should head at a given tick
+type TargetPath func(tick int) ik.Point
+
+// Circle around the center, one lap every period ticks
+func CirclePath(center ik.Point, radius float64, period int) TargetPath {
+	return func(tick int) ik.Point {
+		a := 2 * math.Pi * float64(tick) / float64(period)
+		return ik.Point{X: center.X + radius*math.Cos(a), Y: center.Y + radius*math.Sin(a)}
+	}
+}
+
+// Visit the waypoints in order, holding each for ticksPerPoint ticks, and loop
+func WaypointPath(points []ik.Point, ticksPerPoint int) TargetPath {
+	return func(tick int) ik.Point {
+		return points[(tick/ticksPerPoint)%len(points)]
+	}
+}
+
+// Steps a creature along a target path without ebiten, (for CI and servers)
+type Simulator struct {
+	creature Creature
+	path     TargetPath
+	tick     int
+}
+
+func SimulatorNew(creature Creature, path TargetPath) *Simulator {
+	return &Simulator{creature: creature, path: path}
+}
+
+func (s *Simulator) Tick() int {
+	return s.tick
+}
+
+// Advance the simulation one tick, (TickDuration seconds)
+func (s *Simulator) Step() {
+	s.creature.update(s.path(s.tick), TickDuration)
+	s.tick++
+}
+
+// Step the simulation n ticks, writing the joint positions after every tick as CSV:
+// tick,part,index,x,y  (part is "body" or "limbN", for the other chains)
+func (s *Simulator) Run(n int, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintln(bw, "tick,part,index,x,y"); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		s.Step()
+		if err := s.dump(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (s *Simulator) dump(w io.Writer) error {
+	tick := s.tick - 1
+	for c, chain := range s.creature.chains() {
+		part := "body"
+		if c > 0 {
+			part = fmt.Sprintf("limb%d", c-1)
+		}
+		for i, j := range chain.Joints {
+			if _, err := fmt.Fprintf(w, "%d,%s,%d,%g,%g\n", tick, part, i, j.Pos.X, j.Pos.Y); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestSimulatorRun(t *testing.T) {
+	lizard := LizardNew(WIDTH/2, HEIGHT/2)
+	sim := SimulatorNew(lizard, CirclePath(ik.Point{X: WIDTH, Y: HEIGHT}, 300, 600))
+
+	var buf bytes.Buffer
+	if err := sim.Run(10, &buf); err != nil {
+		t.Fatalf("ERR: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	actual := len(lines)
+	expected := 1 + 10*(len(lizard.chain.Joints)+len(lizard.limbs)*3)
+	if actual != expected {
+		t.Errorf("ERR: actual: %v,  expected: %v", actual, expected)
+	}
+	if sim.Tick() != 10 {
+		t.Errorf("ERR: actual: %v,  expected: %v", sim.Tick(), 10)
+	}
+}
+
+func TestGaitPairsNeverInTheAirTogether(t *testing.T) {
+	for name, pattern := range gaitNames {
+		lizard := LizardNew(WIDTH/2, HEIGHT/2)
+		lizard.gait.SetPattern(pattern)
+		sim := SimulatorNew(lizard, CirclePath(ik.Point{X: WIDTH, Y: HEIGHT}, HEIGHT/2, 1200))
+		// Steps started by each limb, (from standing one tick to swinging the next)
+		steps := make([]int, len(lizard.limbs))
+		swinging := make([]bool, len(lizard.limbs))
+		for i := 0; i < 2000; i++ {
+			sim.Step()
+			for l, limb := range lizard.limbs {
+				p := lizard.gait.pairs[l]
+				if limb.step.Swinging() && lizard.limbs[p].step.Swinging() {
+					t.Fatalf("ERR: %v: limbs %d and %d in the air at tick %d", name, l, p, i)
+				}
+				if limb.step.Swinging() && !swinging[l] {
+					steps[l]++
+				}
+				swinging[l] = limb.step.Swinging()
+			}
+		}
+		for l, n := range steps {
+			if n < 5 {
+				t.Errorf("ERR: %v: limb %d: actual: %v,  expected: at least %v steps", name, l, n, 5)
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// A drawing of creatures kept as outlines rather than pixels, to export. The shapes are painted in order
+type Sketch struct {
+	Width, Height float64
+	Background    color.RGBA // Transparent when the alpha is 0
+	Shapes        []SketchShape
+}
+
+// An outline, filled and/or stroked. Joins are always round
+type SketchShape struct {
+	Outline Outline
+	Fill    *color.RGBA // Not filled when nil
+	Stroke  *color.RGBA // Not stroked when nil
+	Width   float64     // Of the stroke
+	Round   bool        // Round ends of the stroke
+}
+
+// A path, as built by the creatures' path functions, in float64
+type Outline struct {
+	Segments []Segment
+}
+
+// A path segment: 'M'ove to, 'L'ine, 'Q'uadratic and 'C'ubic Bezier, (control points first, the end last), or 'Z' to close
+type Segment struct {
+	Op     byte
+	Points []ik.Point
+}
+
+func SketchNew(width, height float64, background color.RGBA) *Sketch {
+	return &Sketch{Width: width, Height: height, Background: background}
+}
+
+// A sketch of the creature as it is, on the back buffer
+func SketchOf(creature Creature) *Sketch {
+	s := SketchNew(WIDTH*FACTOR, HEIGHT*FACTOR, backgroundColor)
+	creature.sketch(s)
+	return s
+}
+
+func (o *Outline) add(op byte, coords ...float32) {
+	seg := Segment{Op: op}
+	for i := 0; i+1 < len(coords); i += 2 {
+		seg.Points = append(seg.Points, ik.Point{X: float64(coords[i]), Y: float64(coords[i+1])})
+	}
+	o.Segments = append(o.Segments, seg)
+}
+
+func (o *Outline) MoveTo(x, y float32)                    { o.add('M', x, y) }
+func (o *Outline) LineTo(x, y float32)                    { o.add('L', x, y) }
+func (o *Outline) QuadTo(x1, y1, x2, y2 float32)          { o.add('Q', x1, y1, x2, y2) }
+func (o *Outline) CubicTo(x1, y1, x2, y2, x3, y3 float32) { o.add('C', x1, y1, x2, y2, x3, y3) }
+func (o *Outline) Close()                                 { o.add('Z') }
+
+// Fill the outline and stroke its edge, the way drawPath does
+func (s *Sketch) fill(outline Outline, style Style) {
+	fill, stroke := style.Fill, style.Outline
+	s.Shapes = append(s.Shapes, SketchShape{Outline: outline, Fill: &fill, Stroke: &stroke, Width: style.OutlineWidth})
+}
+
+// Stroke a line with round ends
+func (s *Sketch) stroke(outline Outline, width float64, c color.RGBA) {
+	s.Shapes = append(s.Shapes, SketchShape{Outline: outline, Stroke: &c, Width: width, Round: true})
+}
+
+func (s *Sketch) circle(center ik.Point, radius float64, c color.RGBA) {
+	outline := Outline{}
+	appendEllipse(&outline, center, radius, radius, 0)
+	s.Shapes = append(s.Shapes, SketchShape{Outline: outline, Fill: &c})
+}
+
+func (s *Sketch) body(chain *ik.Chain, style Style) {
+	outline := Outline{}
+	appendChainPath(&outline, chain)
+	s.fill(outline, style)
+}
+
+func (s *Sketch) eyes(head *ik.Joint, style Style) {
+	for _, eye := range eyes(head) {
+		s.circle(eye, style.EyeRadius, style.Outline)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const (
+	snakeTurnRate   = 1.2 // Share of the heading error corrected per second
+	snakeAmplitude  = 36  // Sideways swing of the body, at full speed
+	snakeWavelength = 360 // Length of one S of the body, at full speed
+)
+
+// A legless creature that slithers: the body follows the head's track on the ground, with a sine wave laid
+// on top of it. The wave is fixed to the ground, so it travels backwards along the body as the snake moves
+type Snake struct {
+	chain     *ik.Chain
+	speed     float64 // At full speed, pixels per second
+	current   float64 // Speed right now
+	heading   float64
+	trail     []ik.Point // The track of the middle of the wave, newest first
+	travelled float64
+	style     Style
+	// To draw
+	vertices []ebiten.Vertex
+	indices  []uint16
+}
+
+func SnakeNew(x, y int) *Snake {
+	bodyShape := []int{30, 34, 34, 33, 32, 31, 30, 28, 26, 24, 22, 20, 18, 16, 14, 12, 10, 8, 7, 6}
+	distance := 32
+	chain := ik.ChainNew(bodyShape, x, y, distance)
+	style := Style{
+		Fill:         color.RGBA{0x7A, 0x8B, 0x3C, 255},
+		Outline:      color.RGBA{255, 255, 255, 255},
+		OutlineWidth: 3,
+		EyeRadius:    7,
+	}
+	// Start stretched out straight behind the head
+	trail := make([]ik.Point, 0, len(bodyShape))
+	for _, j := range chain.Joints {
+		trail = append(trail, j.Pos)
+	}
+	return &Snake{chain: chain, speed: 180, trail: trail, style: style}
+}
+
+// The sideways swing and the length of the wave, both shrinking as the snake slows down
+func (s *Snake) wave() (amplitude, wavelength float64) {
+	t := s.current / s.speed
+	return snakeAmplitude * t, snakeWavelength * (0.5 + 0.5*t)
+}
+
+func (s *Snake) update(target ik.Point, dt float64) {
+	head := s.trail[0]
+
+	// Slow down when the head is close to the target
+	want := s.speed
+	if ik.Distance(head, target) < s.chain.First().Radius*2 {
+		want = 0
+	}
+	s.current += (want - s.current) * math.Min(1, 3*dt)
+
+	// Turn towards the target, and move along the heading
+	delta := head.Sub(target)
+	turn := math.Atan2(-delta.Y, -delta.X) - s.heading
+	for turn < -math.Pi {
+		turn += 2 * math.Pi
+	}
+	for turn > math.Pi {
+		turn -= 2 * math.Pi
+	}
+	s.heading += turn * math.Min(1, snakeTurnRate*dt)
+	if s.current > 0.6 {
+		step := s.current * dt
+		head = head.Add(ik.Point{X: math.Cos(s.heading) * step, Y: math.Sin(s.heading) * step})
+		s.trail = append([]ik.Point{head}, s.trail...)
+		s.travelled += step
+	}
+	s.pruneTrail()
+
+	// Lay the joints along the track, pushed out sideways by the wave
+	amplitude, wavelength := s.wave()
+	for i, j := range s.chain.Joints {
+		along := float64(i) * s.chain.Distance()
+		pos, dir := s.trailAt(along)
+		offset := amplitude * math.Sin(2*math.Pi*(s.travelled-along)/wavelength)
+		j.Pos = pos.Add(ik.Point{X: -dir.Y * offset, Y: dir.X * offset})
+	}
+
+	// Keep the distances, and point every joint towards the one in front
+	joints := s.chain.Joints
+	for i := 1; i < len(joints); i++ {
+		joints[i].Pos = ik.SetConstraint(joints[i].Pos, joints[i-1].Pos, joints[i-1].Distance)
+		joints[i].Angle = joints[i-1].Pos.Angle(joints[i].Pos)
+	}
+	joints[0].Angle = joints[1].Angle
+}
+
+// The point on the track, and the unit direction of travel there, the given distance behind the head.
+// Continues straight behind the end of the track, if needed
+func (s *Snake) trailAt(along float64) (ik.Point, ik.Point) {
+	for i := 1; i < len(s.trail); i++ {
+		seg := s.trail[i-1].Sub(s.trail[i])
+		l := seg.Mag()
+		if l == 0 {
+			continue
+		}
+		dir := ik.Point{X: seg.X / l, Y: seg.Y / l}
+		if along <= l {
+			return s.trail[i-1].Sub(ik.Point{X: dir.X * along, Y: dir.Y * along}), dir
+		}
+		along -= l
+	}
+	last := s.trail[len(s.trail)-1]
+	dir := ik.Point{X: math.Cos(s.heading), Y: math.Sin(s.heading)}
+	if len(s.trail) > 1 {
+		seg := s.trail[len(s.trail)-2].Sub(last)
+		if l := seg.Mag(); l > 0 {
+			dir = ik.Point{X: seg.X / l, Y: seg.Y / l}
+		}
+	}
+	return last.Sub(ik.Point{X: dir.X * along, Y: dir.Y * along}), dir
+}
+
+// Forget the track behind the tail
+func (s *Snake) pruneTrail() {
+	keep := float64(len(s.chain.Joints)+1) * s.chain.Distance()
+	length := 0.0
+	for i := 1; i < len(s.trail); i++ {
+		length += ik.Distance(s.trail[i-1], s.trail[i])
+		if length > keep {
+			s.trail = s.trail[:i+1]
+			return
+		}
+	}
+}
+
+func (s *Snake) chains() []*ik.Chain {
+	return []*ik.Chain{s.chain}
+}
+
+func (s *Snake) draw(screen *ebiten.Image) {
+	path := chainPath(s.chain)
+	s.vertices, s.indices = drawPath(screen, path, s.style, s.vertices, s.indices)
+	drawEyes(screen, s.chain.First(), s.style)
+}
+
+func (s *Snake) sketch(sk *Sketch) {
+	sk.body(s.chain, s.style)
+	sk.eyes(s.chain.First(), s.style)
+}
+
+func (s *Snake) visitState(v stateVisitor) {
+	visitChain(v, "body", s.chain)
+	v.float("current", &s.current)
+	v.float("heading", &s.heading)
+	v.float("travelled", &s.travelled)
+	visitPoints(v, "trail", &s.trail)
+}
+
+func (s *Snake) debugDraw(screen *ebiten.Image) {
+	for _, j := range s.chain.Joints {
+		drawJointCircle(screen, j)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestSnakeUndulates(t *testing.T) {
+	snake := SnakeNew(WIDTH/2, HEIGHT/2)
+	sim := SimulatorNew(snake, WaypointPath([]ik.Point{{X: 3000, Y: HEIGHT / 2}}, 1))
+	for i := 0; i < 300; i++ {
+		sim.Step()
+	}
+
+	joints := snake.chain.Joints
+	for i := 1; i < len(joints); i++ {
+		d := ik.Distance(joints[i-1].Pos, joints[i].Pos)
+		if math.Abs(d-joints[i-1].Distance) > 1e-9 {
+			t.Errorf("ERR: segment %d: actual: %v,  expected: %v", i, d, joints[i-1].Distance)
+		}
+	}
+
+	// Heading straight right, the body still swings from side to side
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, j := range joints {
+		minY = math.Min(minY, j.Pos.Y)
+		maxY = math.Max(maxY, j.Pos.Y)
+	}
+	if maxY-minY < snakeAmplitude {
+		t.Errorf("ERR: actual: %v,  expected: at least %v", maxY-minY, snakeAmplitude)
+	}
+}
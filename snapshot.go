@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const (
+	snapshotVersion = 1
+	snapshotMagic   = "MSNP"
+	snapshotMax     = 1 << 20 // Values in the binary form, (far more than a crowd has; more is a broken file)
+)
+
+// Visits every changing piece of a creature's state, (not what it was built with), in a fixed order: to save it,
+// or to load it back. Each name is unique within the creature
+type stateVisitor interface {
+	float(name string, v *float64)
+	point(name string, p *ik.Point)
+	// The length of a list that may vary, (e.g. a trail). Loading sets it, before the items are visited
+	length(name string, n *int)
+	// A whole number from 0 to max, (e.g. a state, or a count)
+	count(name string, n *int, max int)
+}
+
+// The state of a creature mid stride, to bookmark, to build test fixtures from, or to resume from. It's loaded
+// back into a creature built the same way, (the same kind, definition and flags)
+type Snapshot struct {
+	Version int                  `json:"version"`
+	Kind    string               `json:"kind"` // "lizard", "snake", "fish" or "world"
+	Tick    int                  `json:"tick"` // Of the target path
+	State   map[string][]float64 `json:"state"`
+	// From the binary form: the values in the order they're visited, and a hash of their names
+	flat  []float64
+	names uint64
+}
+
+func kindOf(c Creature) string {
+	switch c.(type) {
+	case *Lizard:
+		return "lizard"
+	case *Snake:
+		return "snake"
+	case *Fish:
+		return "fish"
+	case *World:
+		return "world"
+	}
+	return fmt.Sprintf("%T", c)
+}
+
+// The creature's state, at the tick
+func TakeSnapshot(c Creature, tick int) *Snapshot {
+	w := &stateWriter{state: map[string][]float64{}, names: fnv.New64a()}
+	c.visitState(w)
+	return &Snapshot{Version: snapshotVersion, Kind: kindOf(c), Tick: tick, State: w.state, flat: w.flat, names: w.names.Sum64()}
+}
+
+// Put the creature back in the snapshot's state. On an error, (a different kind of creature, or built some
+// other way), the creature is left as it was
+func (s *Snapshot) Restore(c Creature) error {
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("snapshot version %d, want %d", s.Version, snapshotVersion)
+	}
+	if kind := kindOf(c); kind != s.Kind {
+		return fmt.Errorf("snapshot of a %s, not a %s", s.Kind, kind)
+	}
+	backup := TakeSnapshot(c, 0)
+	r := &stateReader{state: s.State, flat: s.flat, names: fnv.New64a()}
+	c.visitState(r)
+	if r.err == nil && s.State == nil {
+		if r.next != len(s.flat) {
+			r.err = fmt.Errorf("snapshot has %d values, the creature %d", len(s.flat), r.next)
+		} else if r.names.Sum64() != s.names {
+			r.err = errors.New("snapshot of a creature built some other way")
+		}
+	}
+	if r.err != nil {
+		backup.Restore(c)
+		return r.err
+	}
+	return nil
+}
+
+// Save as JSON if the path ends in .json, otherwise in the compact binary form
+func (s *Snapshot) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(path) == ".json" {
+		err = s.WriteJSON(f)
+	} else {
+		err = s.WriteBinary(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load either form
+func LoadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var s *Snapshot
+	if magic, _ := r.Peek(len(snapshotMagic)); string(magic) == snapshotMagic {
+		s, err = ReadSnapshotBinary(r)
+	} else {
+		s, err = ReadSnapshotJSON(r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *Snapshot) WriteJSON(w io.Writer) error {
+	if s.State == nil {
+		return errors.New("snapshot from the binary form can't be written as JSON")
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+func ReadSnapshotJSON(r io.Reader) (*Snapshot, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var s Snapshot
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.State == nil {
+		return nil, errors.New("snapshot without a state")
+	}
+	return &s, nil
+}
+
+// The binary form: the magic, the version, the kind, the tick, a hash of the names, and the values in the
+// order they're visited. Little endian
+func (s *Snapshot) WriteBinary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(snapshotMagic)
+	binary.Write(bw, binary.LittleEndian, uint16(s.Version))
+	bw.WriteByte(byte(len(s.Kind)))
+	bw.WriteString(s.Kind)
+	binary.Write(bw, binary.LittleEndian, int64(s.Tick))
+	binary.Write(bw, binary.LittleEndian, s.names)
+	binary.Write(bw, binary.LittleEndian, uint32(len(s.flat)))
+	binary.Write(bw, binary.LittleEndian, s.flat)
+	return bw.Flush()
+}
+
+func ReadSnapshotBinary(r io.Reader) (*Snapshot, error) {
+	var header struct {
+		Magic   [4]byte
+		Version uint16
+		KindLen uint8
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Magic[:]) != snapshotMagic {
+		return nil, errors.New("not a snapshot")
+	}
+	kind := make([]byte, header.KindLen)
+	if _, err := io.ReadFull(r, kind); err != nil {
+		return nil, err
+	}
+	var body struct {
+		Tick  int64
+		Names uint64
+		Count uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &body); err != nil {
+		return nil, err
+	}
+	if body.Count > snapshotMax {
+		return nil, fmt.Errorf("snapshot of %d values, at most %d", body.Count, snapshotMax)
+	}
+	flat := make([]float64, body.Count)
+	if err := binary.Read(r, binary.LittleEndian, flat); err != nil {
+		return nil, err
+	}
+	return &Snapshot{Version: int(header.Version), Kind: string(kind), Tick: int(body.Tick), flat: flat, names: body.Names}, nil
+}
+
+// Collects the state, both by name and in order
+type stateWriter struct {
+	state map[string][]float64
+	flat  []float64
+	names hashWriter
+}
+
+type hashWriter interface {
+	io.Writer
+	Sum64() uint64
+}
+
+func (w *stateWriter) put(name string, vs ...float64) {
+	w.state[name] = vs
+	w.flat = append(w.flat, vs...)
+	io.WriteString(w.names, name+"\n")
+}
+
+func (w *stateWriter) float(name string, v *float64)  { w.put(name, *v) }
+func (w *stateWriter) point(name string, p *ik.Point) { w.put(name, p.X, p.Y) }
+func (w *stateWriter) length(name string, n *int)     { w.put(name, float64(*n)) }
+func (w *stateWriter) count(name string, n *int, max int) {
+	w.put(name, float64(*n))
+}
+
+// Sets the state: by name from the JSON form, or in order from the binary form. Stops at the first error
+type stateReader struct {
+	state map[string][]float64
+	flat  []float64
+	next  int
+	names hashWriter
+	err   error
+}
+
+func (r *stateReader) get(name string, n int) []float64 {
+	if r.err != nil {
+		return nil
+	}
+	io.WriteString(r.names, name+"\n")
+	if r.state == nil {
+		if r.next+n > len(r.flat) {
+			r.err = fmt.Errorf("snapshot ends before %s", name)
+			return nil
+		}
+		vs := r.flat[r.next : r.next+n]
+		r.next += n
+		return vs
+	}
+	vs, ok := r.state[name]
+	if !ok || len(vs) != n {
+		r.err = fmt.Errorf("snapshot has no %s", name)
+		return nil
+	}
+	return vs
+}
+
+func (r *stateReader) float(name string, v *float64) {
+	if vs := r.get(name, 1); vs != nil {
+		*v = vs[0]
+	}
+}
+
+func (r *stateReader) point(name string, p *ik.Point) {
+	if vs := r.get(name, 2); vs != nil {
+		*p = ik.Point{X: vs[0], Y: vs[1]}
+	}
+}
+
+// Each item has values of its own, so a list longer than the values left is a broken snapshot, (not one to make
+// room for)
+func (r *stateReader) length(name string, n *int) {
+	left := len(r.state)
+	if r.state == nil {
+		left = len(r.flat) - r.next - 1
+	}
+	r.count(name, n, left)
+}
+
+func (r *stateReader) count(name string, n *int, max int) {
+	if vs := r.get(name, 1); vs != nil {
+		if vs[0] < 0 || vs[0] > float64(max) || vs[0] != math.Trunc(vs[0]) {
+			r.err = fmt.Errorf("snapshot has a bad %s: %v, (0 to %d)", name, vs[0], max)
+			return
+		}
+		*n = int(vs[0])
+	}
+}
+
+// Adds a prefix to the names, for the parts of a creature
+type prefixed struct {
+	v      stateVisitor
+	prefix string
+}
+
+func (p prefixed) float(name string, v *float64)  { p.v.float(p.prefix+name, v) }
+func (p prefixed) point(name string, q *ik.Point) { p.v.point(p.prefix+name, q) }
+func (p prefixed) length(name string, n *int)     { p.v.length(p.prefix+name, n) }
+func (p prefixed) count(name string, n *int, max int) {
+	p.v.count(p.prefix+name, n, max)
+}
+
+// The joints' positions and angles, and where they were the step before, (in Verlet mode)
+func visitChain(v stateVisitor, name string, c *ik.Chain) {
+	for i, j := range c.Joints {
+		v.point(name+"."+strconv.Itoa(i)+".pos", &j.Pos)
+		v.float(name+"."+strconv.Itoa(i)+".angle", &j.Angle)
+	}
+	prev := c.Previous()
+	visitPoints(v, name+".prev", &prev)
+	c.SetPrevious(prev)
+}
+
+// A list of points that may vary in length
+func visitPoints(v stateVisitor, name string, points *[]ik.Point) {
+	n := len(*points)
+	v.length(name, &n)
+	if n != len(*points) {
+		*points = make([]ik.Point, n)
+	}
+	for i := range *points {
+		v.point(name+"."+strconv.Itoa(i), &(*points)[i])
+	}
+}
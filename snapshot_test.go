@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := WaypointPath([]ik.Point{{X: 1800, Y: 400}, {X: 300, Y: 900}}, 1)
+	// Every world is spawned with other random numbers, which the snapshot replaces
+	seed := int64(0)
+	crowd := func() Creature {
+		seed++
+		w := WorldNew(ik.Point{}, ik.Point{X: 2400, Y: 1600})
+		w.Spawn(3, seed, func(x, y int) Creature { return LizardNew(x, y) })
+		return w
+	}
+	tt := []struct {
+		name string
+		new  func() Creature
+	}{
+		{"lizard.json", func() Creature { return LizardNew(600, 400) }},
+		{"snake.bin", func() Creature { return SnakeNew(600, 400) }},
+		{"fish.json", func() Creature { return FishNew(600, 400) }},
+		{"world.bin", crowd},
+	}
+	for _, tc := range tt {
+		original := tc.new()
+		sim := SimulatorNew(original, path)
+		step(sim, 200)
+		file := filepath.Join(t.TempDir(), tc.name)
+		if err := TakeSnapshot(original, sim.Tick()).Save(file); err != nil {
+			t.Fatal(err)
+		}
+		snap, err := LoadSnapshot(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resumed := tc.new()
+		if err := snap.Restore(resumed); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if snap.Tick != 200 || Checksum(resumed) != Checksum(original) {
+			t.Errorf("ERR: actual: %v %016x,  expected: %v %016x (%s)", snap.Tick, Checksum(resumed), 200, Checksum(original), tc.name)
+		}
+
+		// Both carry on the same, bit for bit
+		step(sim, 100)
+		again := SimulatorNew(resumed, path)
+		again.tick = snap.Tick
+		step(again, 100)
+		if Checksum(resumed) != Checksum(original) {
+			t.Errorf("ERR: actual: %016x,  expected: %016x (%s)", Checksum(resumed), Checksum(original), tc.name)
+		}
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	lizard := LizardNew(600, 400)
+	step(SimulatorNew(lizard, WaypointPath([]ik.Point{{X: 1800, Y: 400}}, 1)), 100)
+	snap := TakeSnapshot(lizard, 100)
+
+	// Another kind of creature
+	fish := FishNew(600, 400)
+	before := Checksum(fish)
+	if err := snap.Restore(fish); err == nil || Checksum(fish) != before {
+		t.Errorf("ERR: actual: %v,  expected: an error, and the fish as it was", err)
+	}
+
+	// A lizard built some other way, (without a limb), is left as it was
+	var buf bytes.Buffer
+	if err := snap.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bin, err := ReadSnapshotBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := LizardNew(300, 200)
+	other.limbs = other.limbs[:3]
+	before = Checksum(other)
+	if err := bin.Restore(other); err == nil || Checksum(other) != before {
+		t.Errorf("ERR: actual: %v,  expected: an error, and the lizard as it was", err)
+	}
+
+	// A broken binary form, (that claims far too many values)
+	buf.Reset()
+	bin.flat = nil
+	bin.WriteBinary(&buf)
+	broken := buf.Bytes()
+	copy(broken[len(broken)-4:], []byte{0xff, 0xff, 0xff, 0xff})
+	if _, err := ReadSnapshotBinary(bytes.NewReader(broken)); err == nil {
+		t.Errorf("ERR: actual: %v,  expected: an error", err)
+	}
+
+	// A value missing from the JSON form
+	delete(snap.State, "body.3.pos")
+	other = LizardNew(300, 200)
+	before = Checksum(other)
+	if err := snap.Restore(other); err == nil || Checksum(other) != before {
+		t.Errorf("ERR: actual: %v,  expected: an error, and the lizard as it was", err)
+	}
+}
+
+// Lengths and counts beyond what the snapshot could hold, and states that don't exist, are errors, (not a
+// lot of memory, or time)
+func TestSnapshotBadNumbers(t *testing.T) {
+	lizard := LizardNew(600, 400)
+	step(SimulatorNew(lizard, WaypointPath([]ik.Point{{X: 1800, Y: 400}}, 1)), 100)
+	w := WorldNew(ik.Point{}, ik.Point{X: 2400, Y: 1600})
+	w.Spawn(2, 1, func(x, y int) Creature { return LizardNew(x, y) })
+	step(SimulatorNew(w, WaypointPath([]ik.Point{{X: 1800, Y: 400}}, 1)), 100)
+
+	tt := []struct {
+		creature Creature
+		name     string
+		value    float64
+	}{
+		{lizard, "body.prev", 2e9},
+		{lizard, "body.prev", -1},
+		{lizard, "body.prev", 1.5},
+		{lizard, "limb0.step.state", 1.5},
+		{lizard, "limb0.step.state", 3},
+		{lizard, "limb0.step.state", math.NaN()},
+		{w, "member0.vehicle.draws", 2e9},
+	}
+	for _, tc := range tt {
+		before := Checksum(tc.creature)
+		snap := TakeSnapshot(tc.creature, 100)
+		if _, ok := snap.State[tc.name]; !ok {
+			t.Fatalf("no %s", tc.name)
+		}
+		snap.State[tc.name] = []float64{tc.value}
+		if err := snap.Restore(tc.creature); err == nil || Checksum(tc.creature) != before {
+			t.Errorf("ERR: actual: %v,  expected: an error, and the creature as it was (%s %v)", err, tc.name, tc.value)
+		}
+	}
+
+	// The binary form, where the body's previous positions, (none, out of Verlet mode), follow its joints
+	snap := TakeSnapshot(lizard, 100)
+	var buf bytes.Buffer
+	if err := snap.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bin, err := ReadSnapshotBinary(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin.flat[3*len(lizard.chain.Joints)] = 2e9
+	before := Checksum(lizard)
+	if err := bin.Restore(lizard); err == nil || Checksum(lizard) != before || Checksum(lizard) != before {
+		t.Errorf("ERR: actual: %v,  expected: an error, and the lizard as it was", err)
+	}
+}
+
+func step(sim *Simulator, n int) {
+	for i := 0; i < n; i++ {
+		sim.Step()
+	}
+}
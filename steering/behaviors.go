@@ -0,0 +1,173 @@
+package steering
+
+import (
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// The force that turns the velocity into the desired velocity
+func (v *Vehicle) steerTowards(desired ik.Point) ik.Point {
+	return truncate(desired.Sub(v.Vel), v.MaxForce)
+}
+
+// Head for the target at full speed
+func (v *Vehicle) Seek(target ik.Point) ik.Point {
+	delta := target.Sub(v.Pos)
+	if delta.Mag() == 0 {
+		return ik.Point{}
+	}
+	return v.steerTowards(delta.SetMag(v.MaxSpeed))
+}
+
+// Run from the threat at full speed
+func (v *Vehicle) Flee(threat ik.Point) ik.Point {
+	delta := v.Pos.Sub(threat)
+	if delta.Mag() == 0 {
+		delta = v.heading()
+	}
+	return v.steerTowards(delta.SetMag(v.MaxSpeed))
+}
+
+// Head for the target, slowing down within slowRadius of it, to stop on it
+func (v *Vehicle) Arrive(target ik.Point, slowRadius float64) ik.Point {
+	delta := target.Sub(v.Pos)
+	d := delta.Mag()
+	if d == 0 {
+		return v.steerTowards(ik.Point{})
+	}
+	speed := v.MaxSpeed
+	if d < slowRadius {
+		speed *= d / slowRadius
+	}
+	return v.steerTowards(delta.SetMag(speed))
+}
+
+// Roam: seek a point on a circle ahead of the vehicle, which jitters a little every call.
+// The jitter is the most the point moves along the circle, in radians
+func (v *Vehicle) Wander(distance, radius, jitter float64) ik.Point {
+	v.wander += (v.random()*2 - 1) * jitter
+	h := v.heading()
+	center := v.Pos.Add(scale(h, distance))
+	a := math.Atan2(h.Y, h.X) + v.wander
+	return v.Seek(center.Add(ik.Point{X: math.Cos(a) * radius, Y: math.Sin(a) * radius}))
+}
+
+// Seek where the quarry will be, (guessed from its velocity and how long it takes to get there)
+func (v *Vehicle) Pursue(pos, vel ik.Point) ik.Point {
+	return v.Seek(v.predict(pos, vel))
+}
+
+// Flee from where the pursuer will be
+func (v *Vehicle) Evade(pos, vel ik.Point) ik.Point {
+	return v.Flee(v.predict(pos, vel))
+}
+
+func (v *Vehicle) predict(pos, vel ik.Point) ik.Point {
+	t := 0.0
+	if v.MaxSpeed > 0 {
+		t = ik.Distance(v.Pos, pos) / v.MaxSpeed
+	}
+	return pos.Add(scale(vel, t))
+}
+
+// A round obstacle
+type Circle = ik.Circle
+
+// Steer sideways away from the nearest obstacle in the way, within lookahead seconds along the velocity
+func (v *Vehicle) AvoidObstacles(obstacles []Circle, lookahead float64) ik.Point {
+	h := v.heading()
+	ahead := v.Pos.Add(scale(v.Vel, lookahead))
+	var nearest *Circle
+	nearestDist := math.Inf(1)
+	for i := range obstacles {
+		o := &obstacles[i]
+		closest := ik.ClosestOnSegment(o.Center, v.Pos, ahead)
+		if ik.Distance(closest, o.Center) > o.Radius+v.Radius {
+			continue
+		}
+		if d := ik.Distance(v.Pos, o.Center); d < nearestDist {
+			nearest, nearestDist = o, d
+		}
+	}
+	if nearest == nil {
+		return ik.Point{}
+	}
+	// Push out to the side the obstacle's center isn't on, harder the closer it is
+	side := ik.Point{X: -h.Y, Y: h.X}
+	if nearest.Center.Sub(v.Pos).Dot(side) > 0 {
+		side = scale(side, -1)
+	}
+	reach := math.Max(1, v.Vel.Mag()*lookahead)
+	urgency := math.Max(0, 1-(nearestDist-nearest.Radius-v.Radius)/reach)
+	return scale(side, v.MaxForce*math.Max(0.5, urgency))
+}
+
+// A path of straight segments, with a width
+type Path struct {
+	Points []ik.Point
+	Radius float64 // How far off the path is still on it
+	Loop   bool    // From the last point back to the first
+}
+
+// Stay on the path, heading along it. When the place the vehicle is about to be, (lookahead seconds on),
+// is off the path, seek a point further along the path from there
+func (v *Vehicle) FollowPath(path Path, lookahead float64) ik.Point {
+	n := len(path.Points)
+	if n == 0 {
+		return ik.Point{}
+	}
+	if n == 1 {
+		return v.Arrive(path.Points[0], path.Radius*4)
+	}
+	future := v.Pos.Add(scale(v.heading(), math.Max(v.Vel.Mag(), v.MaxSpeed/4)*lookahead))
+	segments := n - 1
+	if path.Loop {
+		segments = n
+	}
+	best, bestDist, bestSeg := ik.Point{}, math.Inf(1), 0
+	for i := 0; i < segments; i++ {
+		a, b := path.Points[i], path.Points[(i+1)%n]
+		p := ik.ClosestOnSegment(future, a, b)
+		if d := ik.Distance(future, p); d < bestDist {
+			best, bestDist, bestSeg = p, d, i
+		}
+	}
+
+	// At the end of an open path: stop there
+	end := path.Points[n-1]
+	if !path.Loop && bestSeg == segments-1 && ik.Distance(best, end) < path.Radius {
+		return v.Arrive(end, path.Radius*4)
+	}
+	if bestDist <= path.Radius && v.Vel.Dot(path.Points[(bestSeg+1)%n].Sub(path.Points[bestSeg])) > 0 {
+		return ik.Point{}
+	}
+	// Aim a bit further along the segment
+	a, b := path.Points[bestSeg], path.Points[(bestSeg+1)%n]
+	along := b.Sub(a)
+	if along.Mag() > 0 {
+		best = best.Add(along.SetMag(math.Min(path.Radius*2, ik.Distance(best, b))))
+	}
+	return v.Seek(best)
+}
+
+// Steer back in when within margin of the edges of the area, (min and max corners)
+func (v *Vehicle) Contain(min, max ik.Point, margin float64) ik.Point {
+	desired := v.Vel
+	switch {
+	case v.Pos.X < min.X+margin:
+		desired.X = v.MaxSpeed
+	case v.Pos.X > max.X-margin:
+		desired.X = -v.MaxSpeed
+	}
+	switch {
+	case v.Pos.Y < min.Y+margin:
+		desired.Y = v.MaxSpeed
+	case v.Pos.Y > max.Y-margin:
+		desired.Y = -v.MaxSpeed
+	}
+	if desired == v.Vel {
+		return ik.Point{}
+	}
+	return v.steerTowards(truncate(desired, v.MaxSpeed))
+}
@@ -0,0 +1,48 @@
+package steering
+
+import "github.com/mpja69/moving_snakes/ik"
+
+// Steer away from the others within radius, harder the closer they are. The others can be any points,
+// (e.g. all joints of the neighbors' bodies)
+func (v *Vehicle) Separate(others []ik.Point, radius float64) ik.Point {
+	push := ik.Point{}
+	for _, p := range others {
+		away := v.Pos.Sub(p)
+		d := away.Mag()
+		if d == 0 || d > radius {
+			continue
+		}
+		push = push.Add(scale(away, (radius-d)/(radius*d)))
+	}
+	if push.Mag() == 0 {
+		return ik.Point{}
+	}
+	return v.steerTowards(push.SetMag(v.MaxSpeed))
+}
+
+// Match the average velocity of the neighbors
+func (v *Vehicle) Align(velocities []ik.Point) ik.Point {
+	if len(velocities) == 0 {
+		return ik.Point{}
+	}
+	sum := ik.Point{}
+	for _, vel := range velocities {
+		sum = sum.Add(vel)
+	}
+	if sum.Mag() == 0 {
+		return ik.Point{}
+	}
+	return v.steerTowards(sum.SetMag(v.MaxSpeed))
+}
+
+// Seek the center of the neighbors
+func (v *Vehicle) Cohere(positions []ik.Point) ik.Point {
+	if len(positions) == 0 {
+		return ik.Point{}
+	}
+	sum := ik.Point{}
+	for _, p := range positions {
+		sum = sum.Add(p)
+	}
+	return v.Seek(scale(sum, 1/float64(len(positions))))
+}
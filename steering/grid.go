@@ -0,0 +1,55 @@
+package steering
+
+import (
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// A spatial index of points in square cells, for finding the points near a place without looking at them all
+type Grid struct {
+	cell  float64
+	cells map[[2]int][]gridEntry
+}
+
+type gridEntry struct {
+	id  int
+	pos ik.Point
+}
+
+// Queries are fastest with the cell size close to the query radius
+func GridNew(cell float64) *Grid {
+	return &Grid{cell: cell, cells: map[[2]int][]gridEntry{}}
+}
+
+// Remove all points, keeping the memory
+func (g *Grid) Clear() {
+	for k, entries := range g.cells {
+		g.cells[k] = entries[:0]
+	}
+}
+
+func (g *Grid) key(p ik.Point) [2]int {
+	return [2]int{int(math.Floor(p.X / g.cell)), int(math.Floor(p.Y / g.cell))}
+}
+
+// Add a point, with an id of the caller's choice, (e.g. the index of the creature it belongs to)
+func (g *Grid) Insert(id int, p ik.Point) {
+	k := g.key(p)
+	g.cells[k] = append(g.cells[k], gridEntry{id, p})
+}
+
+// Call fn for every point within radius of p
+func (g *Grid) Near(p ik.Point, radius float64, fn func(id int, pos ik.Point)) {
+	lo := g.key(ik.Point{X: p.X - radius, Y: p.Y - radius})
+	hi := g.key(ik.Point{X: p.X + radius, Y: p.Y + radius})
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for _, e := range g.cells[[2]int{x, y}] {
+				if ik.Distance(p, e.pos) <= radius {
+					fn(e.id, e.pos)
+				}
+			}
+		}
+	}
+}
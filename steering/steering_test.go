@@ -0,0 +1,147 @@
+package steering
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+const dt = 1.0 / 60
+
+func run(v *Vehicle, ticks int, steer func(v *Vehicle) ik.Point) {
+	for i := 0; i < ticks; i++ {
+		v.Apply(steer(v), 1)
+		v.Update(dt)
+	}
+}
+
+func TestArriveStops(t *testing.T) {
+	v := VehicleNew(ik.Point{}, 100, 200, 1)
+	target := ik.Point{X: 300, Y: 200}
+	run(v, 1200, func(v *Vehicle) ik.Point { return v.Arrive(target, 100) })
+	if d := ik.Distance(v.Pos, target); d > 1 {
+		t.Errorf("ERR: distance: actual: %v,  expected: below %v", d, 1)
+	}
+	if s := v.Vel.Mag(); s > 1 {
+		t.Errorf("ERR: speed: actual: %v,  expected: below %v", s, 1)
+	}
+}
+
+func TestFleeAndEvade(t *testing.T) {
+	v := VehicleNew(ik.Point{X: 10, Y: 0}, 100, 200, 1)
+	run(v, 120, func(v *Vehicle) ik.Point { return v.Flee(ik.Point{}) })
+	if v.Pos.X < 100 {
+		t.Errorf("ERR: actual: %v,  expected: away from the threat", v.Pos)
+	}
+
+	// A pursuer coming straight down at the evader: it moves off to the side, and keeps away
+	v = VehicleNew(ik.Point{}, 100, 200, 1)
+	pursuer := VehicleNew(ik.Point{X: 0, Y: -200}, 80, 200, 1)
+	run(v, 300, func(v *Vehicle) ik.Point {
+		pursuer.Apply(pursuer.Pursue(v.Pos, v.Vel), 1)
+		pursuer.Update(dt)
+		return v.Evade(pursuer.Pos, pursuer.Vel)
+	})
+	if d := ik.Distance(v.Pos, pursuer.Pos); d < 150 {
+		t.Errorf("ERR: actual: %v,  expected: at least %v", d, 150)
+	}
+}
+
+func TestPursueCatches(t *testing.T) {
+	quarry := ik.Point{X: 0, Y: 300}
+	quarryVel := ik.Point{X: 50, Y: 0}
+	v := VehicleNew(ik.Point{}, 100, 200, 1)
+	caught := false
+	for i := 0; i < 600 && !caught; i++ {
+		quarry = quarry.Add(scale(quarryVel, dt))
+		v.Apply(v.Pursue(quarry, quarryVel), 1)
+		v.Update(dt)
+		caught = ik.Distance(v.Pos, quarry) < 5
+	}
+	if !caught {
+		t.Errorf("ERR: actual: %v,  expected: close to %v", v.Pos, quarry)
+	}
+}
+
+func TestWanderIsRepeatable(t *testing.T) {
+	a := VehicleNew(ik.Point{}, 100, 200, 7)
+	b := VehicleNew(ik.Point{}, 100, 200, 7)
+	wander := func(v *Vehicle) ik.Point { return v.Wander(100, 50, 0.5) }
+	run(a, 300, wander)
+	run(b, 300, wander)
+	if a.Pos != b.Pos {
+		t.Errorf("ERR: actual: %v,  expected: %v", a.Pos, b.Pos)
+	}
+	if a.Vel.Mag() < 50 {
+		t.Errorf("ERR: actual: %v,  expected: moving", a.Vel.Mag())
+	}
+}
+
+func TestAvoidObstacles(t *testing.T) {
+	v := VehicleNew(ik.Point{}, 100, 200, 1)
+	v.Radius = 10
+	target := ik.Point{X: 600, Y: 0}
+	rock := []Circle{{Center: ik.Point{X: 300, Y: 5}, Radius: 50}}
+	closest := math.Inf(1)
+	run(v, 1200, func(v *Vehicle) ik.Point {
+		closest = math.Min(closest, ik.Distance(v.Pos, rock[0].Center))
+		v.Apply(v.AvoidObstacles(rock, 1), 3)
+		return v.Arrive(target, 100)
+	})
+	if closest < rock[0].Radius+v.Radius {
+		t.Errorf("ERR: actual: %v,  expected: at least %v", closest, rock[0].Radius+v.Radius)
+	}
+	if d := ik.Distance(v.Pos, target); d > 5 {
+		t.Errorf("ERR: actual: %v,  expected: at %v", v.Pos, target)
+	}
+}
+
+func TestFollowPath(t *testing.T) {
+	path := Path{Points: []ik.Point{{X: 0, Y: 0}, {X: 400, Y: 0}, {X: 400, Y: 400}, {X: 0, Y: 400}}, Radius: 20, Loop: true}
+	v := VehicleNew(ik.Point{X: 50, Y: 60}, 100, 300, 1)
+	corner := path.Points[2]
+	nearCorner := math.Inf(1)
+	worst := 0.0
+	run(v, 1800, func(v *Vehicle) ik.Point {
+		nearCorner = math.Min(nearCorner, ik.Distance(v.Pos, corner))
+		if d := distanceToPath(v.Pos, path); d > worst && v.Pos.X > 200 {
+			worst = d
+		}
+		return v.FollowPath(path, 0.5)
+	})
+	// Once on it, it stays close to the path, and goes round
+	if worst > 3*path.Radius {
+		t.Errorf("ERR: actual: %v,  expected: at most %v", worst, 3*path.Radius)
+	}
+	if nearCorner > 3*path.Radius {
+		t.Errorf("ERR: actual: %v,  expected: at most %v", nearCorner, 3*path.Radius)
+	}
+}
+
+func distanceToPath(p ik.Point, path Path) float64 {
+	best := math.Inf(1)
+	for i := range path.Points {
+		a, b := path.Points[i], path.Points[(i+1)%len(path.Points)]
+		best = math.Min(best, ik.Distance(p, ik.ClosestOnSegment(p, a, b)))
+	}
+	return best
+}
+
+func TestGridNear(t *testing.T) {
+	g := GridNew(50)
+	points := []ik.Point{}
+	for i := 0; i < 400; i++ {
+		p := ik.Point{X: float64((i * 37) % 500), Y: float64((i * 91) % 500)}
+		points = append(points, p)
+		g.Insert(i, p)
+	}
+	center := ik.Point{X: 240, Y: 260}
+	found := map[int]bool{}
+	g.Near(center, 75, func(id int, pos ik.Point) { found[id] = true })
+	for i, p := range points {
+		if near := ik.Distance(center, p) <= 75; near != found[i] {
+			t.Errorf("ERR: point %d: actual: %v,  expected: %v", i, found[i], near)
+		}
+	}
+}
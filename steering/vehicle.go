@@ -0,0 +1,96 @@
+// Package steering contains steering behaviors, (seek, flee, arrive, wander, pursue, evade, obstacle
+// avoidance and path following), that let creatures move on their own. They produce targets to head for.
+package steering
+
+import (
+	"math/rand"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// A point mass that is steered by forces. Put it at the creature's head every tick, and head for its Target
+type Vehicle struct {
+	Pos       ik.Point
+	Vel       ik.Point // Pixels per second
+	MaxSpeed  float64  // Pixels per second
+	MaxForce  float64  // Pixels per second squared
+	Radius    float64  // Of the body, to keep clear of obstacles
+	Lookahead float64  // Seconds ahead along the velocity the target is put
+	force     ik.Point
+	wander    float64 // Angle on the wander circle
+	rand      *rand.Rand
+	seed      int64
+	draws     int // Random numbers drawn so far
+}
+
+// The seed makes wandering repeatable
+func VehicleNew(pos ik.Point, maxSpeed, maxForce float64, seed int64) *Vehicle {
+	return &Vehicle{Pos: pos, MaxSpeed: maxSpeed, MaxForce: maxForce, Lookahead: 1, rand: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+// Where on the wander circle the vehicle is heading, (radians from its heading)
+func (v *Vehicle) WanderAngle() float64 {
+	return v.wander
+}
+
+func (v *Vehicle) SetWanderAngle(a float64) {
+	v.wander = a
+}
+
+// The seed, and how many random numbers have been drawn from it, (to go on wandering the same way later)
+func (v *Vehicle) RandState() (seed int64, draws int) {
+	return v.seed, v.draws
+}
+
+// Go on from that many random numbers into the seed, (by drawing them again)
+func (v *Vehicle) SetRandState(seed int64, draws int) {
+	if seed != v.seed || draws < v.draws {
+		v.rand, v.seed, v.draws = rand.New(rand.NewSource(seed)), seed, 0
+	}
+	for v.draws < draws {
+		v.random()
+	}
+}
+
+func (v *Vehicle) random() float64 {
+	v.draws++
+	return v.rand.Float64()
+}
+
+// Add a steering force, (from one of the behaviors), scaled by the weight
+func (v *Vehicle) Apply(force ik.Point, weight float64) {
+	v.force = v.force.Add(scale(force, weight))
+}
+
+// Move the vehicle dt seconds on, by the forces applied since the last update, (limited to MaxForce)
+func (v *Vehicle) Update(dt float64) {
+	v.Vel = truncate(v.Vel.Add(scale(truncate(v.force, v.MaxForce), dt)), v.MaxSpeed)
+	v.Pos = v.Pos.Add(scale(v.Vel, dt))
+	v.force = ik.Point{}
+}
+
+// Where the creature should head: ahead of the vehicle along its velocity. Close to the vehicle when it's slow,
+// so a creature that stops within its head's length of the target stops too
+func (v *Vehicle) Target() ik.Point {
+	return v.Pos.Add(scale(v.Vel, v.Lookahead))
+}
+
+// The unit vector of the heading, (to the right when standing still)
+func (v *Vehicle) heading() ik.Point {
+	if v.Vel.Mag() == 0 {
+		return ik.Point{X: 1}
+	}
+	return scale(v.Vel, 1/v.Vel.Mag())
+}
+
+func scale(p ik.Point, k float64) ik.Point {
+	return ik.Point{X: p.X * k, Y: p.Y * k}
+}
+
+// Shorten p to at most max
+func truncate(p ik.Point, max float64) ik.Point {
+	if m := p.Mag(); m > max {
+		return scale(p, max/m)
+	}
+	return p
+}
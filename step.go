@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// Maps the progress of a step, 0 to 1, to how far along the foot has travelled
+type Easing func(t float64) float64
+
+func EaseLinear(t float64) float64 {
+	return t
+}
+
+func EaseInOutSine(t float64) float64 {
+	return -(math.Cos(math.Pi*t) - 1) / 2
+}
+
+func EaseInOutCubic(t float64) float64 {
+	if t < 0.5 {
+		return 4 * t * t * t
+	}
+	return 1 - math.Pow(-2*t+2, 3)/2
+}
+
+type footState int
+
+const (
+	footUnplaced footState = iota // Not on the ground yet: lands directly
+	footPlanted
+	footSwinging
+)
+
+// The stepping state machine of a foot: planted, or swinging along an arc to a new foothold
+type Stepper struct {
+	Duration float64 // Seconds a step takes
+	Lift     float64 // How far the arc bulges out from the straight line, at the middle of the step
+	Ease     Easing
+	state    footState
+	from, to ik.Point
+	progress float64 // 0 to 1
+	side     float64 // Which way the arc bulges: 1 or -1
+}
+
+func StepperNew(duration, lift float64, ease Easing) Stepper {
+	return Stepper{Duration: duration, Lift: lift, Ease: ease}
+}
+
+// True when the foot is on the ground
+func (s *Stepper) Planted() bool {
+	return s.state == footPlanted
+}
+
+// False until the foot has landed the first time
+func (s *Stepper) Placed() bool {
+	return s.state != footUnplaced
+}
+
+func (s *Stepper) Swinging() bool {
+	return s.state == footSwinging
+}
+
+func (s *Stepper) visitState(v stateVisitor) {
+	state := int(s.state)
+	v.count("state", &state, int(footSwinging))
+	s.state = footState(state)
+	v.point("from", &s.from)
+	v.point("to", &s.to)
+	v.float("progress", &s.progress)
+	v.float("side", &s.side)
+}
+
+// Lift the foot off the ground, towards a new foothold. The arc bulges to the right of the step for side > 0
+func (s *Stepper) Start(from, to ik.Point, side float64) {
+	if s.state == footUnplaced {
+		s.from, s.to = to, to
+		s.state = footPlanted
+		return
+	}
+	s.from, s.to = from, to
+	s.progress = 0
+	s.side = math.Copysign(1, side)
+	s.state = footSwinging
+}
+
+// Move the foothold of an ongoing step, (e.g. since the body has moved on)
+func (s *Stepper) Retarget(to ik.Point) {
+	if s.state == footSwinging {
+		s.to = to
+	}
+}
+
+// Advance the step dt seconds, and return where the foot is. Lands, (is planted), when the step is done
+func (s *Stepper) Advance(dt float64) ik.Point {
+	if s.state != footSwinging {
+		return s.to
+	}
+	s.progress = math.Min(1, s.progress+dt/math.Max(dt, s.Duration))
+	if s.progress >= 1-1e-9 {
+		s.state = footPlanted
+		return s.to
+	}
+	return s.Pos()
+}
+
+// Where the foot is now, along the arc
+func (s *Stepper) Pos() ik.Point {
+	if s.state != footSwinging {
+		return s.to
+	}
+	t := s.Ease(s.progress)
+	step := s.to.Sub(s.from)
+	pos := ik.Point{X: s.from.X + step.X*t, Y: s.from.Y + step.Y*t}
+	if step.Mag() == 0 {
+		return pos
+	}
+	// Bulge out perpendicular to the step, highest at the middle
+	lift := s.Lift * math.Sin(math.Pi*s.progress) * s.side
+	perp := ik.Point{X: -step.Y, Y: step.X}.SetMag(lift)
+	return pos.Add(perp)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+func TestStepperSwing(t *testing.T) {
+	s := StepperNew(0.4, 10, EaseLinear)
+	from := ik.Point{X: 0, Y: 0}
+	to := ik.Point{X: 40, Y: 0}
+
+	// The first step lands directly
+	s.Start(to, from, 1)
+	if !s.Planted() || s.Advance(0.1) != from {
+		t.Errorf("ERR: actual: %v,  expected: planted at %v", s.Pos(), from)
+	}
+
+	s.Start(from, to, 1)
+	var path []ik.Point
+	for !s.Planted() {
+		path = append(path, s.Advance(0.1))
+	}
+	if len(path) != 4 {
+		t.Errorf("ERR: actual: %v,  expected: %v", len(path), 4)
+	}
+	// Halfway, the foot is lifted the most, (to the right of the step)
+	mid := path[1]
+	if math.Abs(mid.X-20) > 1e-9 || math.Abs(mid.Y-10) > 1e-9 {
+		t.Errorf("ERR: actual: %v,  expected: %v", mid, ik.Point{X: 20, Y: 10})
+	}
+	if path[3] != to {
+		t.Errorf("ERR: actual: %v,  expected: %v", path[3], to)
+	}
+}
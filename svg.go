@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Write the sketch as an SVG document, with the paths as they are built: cubic and quadratic Beziers, not polygons
+func (s *Sketch) WriteSVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"0 0 %[1]s %[2]s\">\n",
+		num(s.Width), num(s.Height))
+	if s.Background.A > 0 {
+		fmt.Fprintf(bw, "  <rect width=\"100%%\" height=\"100%%\" fill=\"%s\"/>\n", hex(s.Background))
+	}
+	for _, shape := range s.Shapes {
+		fmt.Fprintf(bw, "  <path d=\"%s\"", shape.Outline.svgData())
+		if shape.Fill != nil {
+			fmt.Fprintf(bw, " fill=\"%s\"", hex(*shape.Fill))
+		} else {
+			fmt.Fprint(bw, " fill=\"none\"")
+		}
+		if shape.Stroke != nil && shape.Width > 0 {
+			fmt.Fprintf(bw, " stroke=\"%s\" stroke-width=\"%s\" stroke-linejoin=\"round\"", hex(*shape.Stroke), num(shape.Width))
+			if shape.Round {
+				fmt.Fprint(bw, " stroke-linecap=\"round\"")
+			}
+		}
+		fmt.Fprint(bw, "/>\n")
+	}
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+func (s *Sketch) SaveSVG(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.WriteSVG(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// The path data, ("M x y C ..."), with the same letters as the segments
+func (o *Outline) svgData() string {
+	var b strings.Builder
+	for i, seg := range o.Segments {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte(seg.Op)
+		for _, p := range seg.Points {
+			b.WriteByte(' ')
+			b.WriteString(num(p.X))
+			b.WriteByte(' ')
+			b.WriteString(num(p.Y))
+		}
+	}
+	return b.String()
+}
+
+// A coordinate, to two decimals, without trailing zeros
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
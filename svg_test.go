@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+type svgDoc struct {
+	Width string    `xml:"width,attr"`
+	Paths []svgPath `xml:"path"`
+}
+
+type svgPath struct {
+	D      string `xml:"d,attr"`
+	Fill   string `xml:"fill,attr"`
+	Stroke string `xml:"stroke,attr"`
+}
+
+func TestLizardSVG(t *testing.T) {
+	lizard := LizardNew(600, 400)
+	sim := SimulatorNew(lizard, CirclePath(ik.Point{X: WIDTH, Y: HEIGHT}, HEIGHT/2, 1200))
+	for i := 0; i < 120; i++ {
+		sim.Step()
+	}
+	var buf bytes.Buffer
+	if err := SketchOf(lizard).WriteSVG(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc svgDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("ERR: actual: %v,  expected: a valid SVG document", err)
+	}
+
+	// Two strokes per limb, the body, and two eyes
+	if len(doc.Paths) != 2*4+1+2 {
+		t.Fatalf("ERR: actual: %v,  expected: %v", len(doc.Paths), 2*4+1+2)
+	}
+	body := doc.Paths[8]
+	if body.Fill != "#58857A" || body.Stroke != "#FFFFFF" {
+		t.Errorf("ERR: actual: %v %v,  expected: %v %v", body.Fill, body.Stroke, "#58857A", "#FFFFFF")
+	}
+	// Around the body in curves, starting on the right of the head
+	head := lizard.chain.First().Right()
+	if start := "M " + num(float64(float32(head.X))) + " " + num(float64(float32(head.Y))) + " C "; !strings.HasPrefix(body.D, start) {
+		t.Errorf("ERR: actual: %.40v,  expected: %v...", body.D, start)
+	}
+	if strings.Count(body.D, "C") < 2*len(lizard.chain.Joints) {
+		t.Errorf("ERR: actual: %v,  expected: a curve through every joint", body.D)
+	}
+	for _, limb := range doc.Paths[:8] {
+		if limb.Fill != "none" || !strings.Contains(limb.D, "C") {
+			t.Errorf("ERR: actual: %v,  expected: a curved stroke", limb)
+		}
+	}
+}
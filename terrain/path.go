@@ -0,0 +1,217 @@
+package terrain
+
+import (
+	"container/heap"
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// A shortest path for a circle of the clearance radius, from one point to another, around the obstacles.
+// The path is searched on the grid, and then straightened where the line of sight allows. It starts with the
+// first waypoint after from, and ends at to, (or the free place closest to it, if to is in an obstacle).
+// False if there is no way there
+func (t *Terrain) FindPath(from, to ik.Point, clearance float64) ([]ik.Point, bool) {
+	free := t.grid(clearance)
+	w, h := t.size()
+	start, ok := t.cell(from)
+	if !ok {
+		return nil, false
+	}
+	goal, ok := t.cell(to)
+	if !ok {
+		return nil, false
+	}
+	if !free[goal] {
+		if goal, ok = t.nearestFree(goal, free); !ok {
+			return nil, false
+		}
+		to = t.center(goal)
+	}
+
+	// The start may be blocked, (e.g. squeezed against a wall, or pushed into it): it's always open, and when no
+	// neighbor is free the way out starts at the closest free cell
+	var exit []ik.Point
+	if !free[start] && !t.freeNeighbor(start, free) {
+		out, ok := t.nearestFree(start, free)
+		if !ok {
+			return nil, false
+		}
+		start = out
+		exit = []ik.Point{t.center(out)}
+	}
+
+	// A* over the 8 neighbors
+	const none = -1
+	cost := make([]float64, w*h)
+	came := make([]int, w*h)
+	for i := range cost {
+		cost[i] = math.Inf(1)
+		came[i] = none
+	}
+	cost[start] = 0
+	open := &cellHeap{{start, t.heuristic(start, goal)}}
+	for open.Len() > 0 {
+		c := heap.Pop(open).(cellCost).cell
+		if c == goal {
+			break
+		}
+		cx, cy := c%w, c/w
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := cx+dx, cy+dy
+				if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= w || ny >= h {
+					continue
+				}
+				n := ny*w + nx
+				if !free[n] {
+					continue
+				}
+				// No cutting corners, (except out of the start, which may be in one)
+				if dx != 0 && dy != 0 && c != start && (!free[cy*w+nx] || !free[ny*w+cx]) {
+					continue
+				}
+				step := math.Hypot(float64(dx), float64(dy))
+				if c2 := cost[c] + step; c2 < cost[n] {
+					cost[n] = c2
+					came[n] = c
+					heap.Push(open, cellCost{n, c2 + t.heuristic(n, goal)})
+				}
+			}
+		}
+	}
+	if start != goal && came[goal] == none {
+		return nil, false
+	}
+
+	// Walk back from the goal, and straighten
+	cells := []ik.Point{to}
+	for c := came[goal]; c != none && c != start; c = came[c] {
+		cells = append(cells, t.center(c))
+	}
+	cells = append(cells, exit...)
+	path := []ik.Point{}
+	at := from
+	for i := len(cells) - 1; i >= 0; {
+		// The furthest waypoint in sight
+		j := 0
+		for j < i && !t.inSight(at, cells[j], clearance) {
+			j++
+		}
+		path = append(path, cells[j])
+		at = cells[j]
+		i = j - 1
+	}
+	return path, true
+}
+
+// True if a circle of the clearance can move straight from a to b
+func (t *Terrain) inSight(a, b ik.Point, clearance float64) bool {
+	d := ik.Distance(a, b)
+	n := int(math.Ceil(d / (t.Cell / 2)))
+	for i := 1; i <= n; i++ {
+		s := float64(i) / float64(n)
+		if !t.Free(ik.Point{X: a.X + (b.X-a.X)*s, Y: a.Y + (b.Y-a.Y)*s}, clearance) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Terrain) size() (int, int) {
+	return int(math.Ceil((t.Max.X - t.Min.X) / t.Cell)), int(math.Ceil((t.Max.Y - t.Min.Y) / t.Cell))
+}
+
+func (t *Terrain) cell(p ik.Point) (int, bool) {
+	w, h := t.size()
+	x := int(math.Floor((p.X - t.Min.X) / t.Cell))
+	y := int(math.Floor((p.Y - t.Min.Y) / t.Cell))
+	x = max(0, min(w-1, x))
+	y = max(0, min(h-1, y))
+	return y*w + x, w > 0 && h > 0
+}
+
+func (t *Terrain) center(c int) ik.Point {
+	w, _ := t.size()
+	return ik.Point{X: t.Min.X + (float64(c%w)+0.5)*t.Cell, Y: t.Min.Y + (float64(c/w)+0.5)*t.Cell}
+}
+
+func (t *Terrain) heuristic(a, b int) float64 {
+	w, _ := t.size()
+	return math.Hypot(float64(a%w-b%w), float64(a/w-b/w))
+}
+
+// Which cells a circle of the clearance fits in, (computed once per clearance, since the terrain doesn't move)
+func (t *Terrain) grid(clearance float64) []bool {
+	if free, ok := t.grids[clearance]; ok {
+		return free
+	}
+	w, h := t.size()
+	free := make([]bool, w*h)
+	for c := range free {
+		free[c] = t.Free(t.center(c), clearance)
+	}
+	if t.grids == nil {
+		t.grids = map[float64][]bool{}
+	}
+	t.grids[clearance] = free
+	return free
+}
+
+// True if any of the 8 cells around c is free
+func (t *Terrain) freeNeighbor(c int, free []bool) bool {
+	w, h := t.size()
+	cx, cy := c%w, c/w
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := cx+dx, cy+dy
+			if (dx != 0 || dy != 0) && nx >= 0 && ny >= 0 && nx < w && ny < h && free[ny*w+nx] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The free cell closest to c, searched breadth first
+func (t *Terrain) nearestFree(c int, free []bool) (int, bool) {
+	w, h := t.size()
+	seen := make([]bool, w*h)
+	queue := []int{c}
+	seen[c] = true
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if free[c] {
+			return c, true
+		}
+		cx, cy := c%w, c/w
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			nx, ny := cx+d[0], cy+d[1]
+			if nx < 0 || ny < 0 || nx >= w || ny >= h || seen[ny*w+nx] {
+				continue
+			}
+			seen[ny*w+nx] = true
+			queue = append(queue, ny*w+nx)
+		}
+	}
+	return 0, false
+}
+
+type cellCost struct {
+	cell int
+	cost float64 // So far plus the heuristic
+}
+
+type cellHeap []cellCost
+
+func (h cellHeap) Len() int           { return len(h) }
+func (h cellHeap) Less(i, j int) bool { return h[i].cost < h[j].cost }
+func (h cellHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *cellHeap) Push(x any)        { *h = append(*h, x.(cellCost)) }
+func (h *cellHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
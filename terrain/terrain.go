@@ -0,0 +1,155 @@
+// Package terrain contains the static world the creatures move in: obstacles nothing may pass through,
+// regions feet can't be put down in, foothold search and path finding around the obstacles.
+package terrain
+
+import (
+	"math"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// An area of the world
+type Shape interface {
+	// The closest point on the outline, and whether p is inside
+	Closest(p ik.Point) (ik.Point, bool)
+}
+
+// A round area, (the same as the obstacles the steering behaviors avoid)
+type Circle = ik.Circle
+
+// A closed polygon, (the last point connects back to the first)
+type Polygon struct {
+	Points []ik.Point
+}
+
+// A straight wall, with rounded ends
+type Wall struct {
+	From, To ik.Point
+	Width    float64
+}
+
+func (w Wall) Closest(p ik.Point) (ik.Point, bool) {
+	return Circle{Center: ik.ClosestOnSegment(p, w.From, w.To), Radius: w.Width / 2}.Closest(p)
+}
+
+func (poly Polygon) Closest(p ik.Point) (ik.Point, bool) {
+	n := len(poly.Points)
+	best, bestDist := p, math.Inf(1)
+	inside := false
+	for i := 0; i < n; i++ {
+		a, b := poly.Points[i], poly.Points[(i+1)%n]
+		q := ik.ClosestOnSegment(p, a, b)
+		if d := ik.Distance(p, q); d < bestDist {
+			best, bestDist = q, d
+		}
+		// Count the edges a ray to the right crosses
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < a.X+(p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return best, inside
+}
+
+// The distance from p to the outline: negative inside the shape
+func SignedDistance(s Shape, p ik.Point) float64 {
+	q, inside := s.Closest(p)
+	if inside {
+		return -ik.Distance(p, q)
+	}
+	return ik.Distance(p, q)
+}
+
+// The static world: obstacles, and where feet may be put down. Paths are searched on a grid of square cells
+// within the bounds. Call Changed after editing the obstacles
+type Terrain struct {
+	Obstacles  []Shape // Nothing may pass through
+	Unwalkable []Shape // Bodies may pass over, but feet can't be put down, (e.g. water)
+	Min, Max   ik.Point
+	Cell       float64
+	grids      map[float64][]bool // Free cells, by clearance
+}
+
+func TerrainNew(min, max ik.Point, cell float64) *Terrain {
+	return &Terrain{Min: min, Max: max, Cell: cell}
+}
+
+// Forget the free cells found so far, (after the obstacles changed)
+func (t *Terrain) Changed() {
+	t.grids = nil
+}
+
+// How far p is from the nearest obstacle, (negative inside one)
+func (t *Terrain) Clearance(p ik.Point) float64 {
+	c := math.Inf(1)
+	for _, o := range t.Obstacles {
+		c = math.Min(c, SignedDistance(o, p))
+	}
+	return c
+}
+
+// True if a circle of the radius fits at p
+func (t *Terrain) Free(p ik.Point, radius float64) bool {
+	return t.Clearance(p) >= radius
+}
+
+// True if a foot may be put down at p
+func (t *Terrain) Walkable(p ik.Point) bool {
+	if !t.Free(p, 0) {
+		return false
+	}
+	for _, u := range t.Unwalkable {
+		if _, inside := u.Closest(p); inside {
+			return false
+		}
+	}
+	return true
+}
+
+// The walkable point closest to want, searched in rings out to within, (of the equally close ones, the furthest
+// ahead, in the direction of the heading). False if there is none
+func (t *Terrain) Foothold(want, heading ik.Point, within float64) (ik.Point, bool) {
+	if t.Walkable(want) {
+		return want, true
+	}
+	const rings = 8
+	for r := 1; r <= rings; r++ {
+		radius := within * float64(r) / rings
+		best, bestAhead := ik.Point{}, math.Inf(-1)
+		n := 6 * r
+		for k := 0; k < n; k++ {
+			a := 2 * math.Pi * float64(k) / float64(n)
+			p := want.Add(ik.Point{X: radius * math.Cos(a), Y: radius * math.Sin(a)})
+			if !t.Walkable(p) {
+				continue
+			}
+			if ahead := p.Sub(want).Dot(heading); ahead > bestAhead {
+				best, bestAhead = p, ahead
+			}
+		}
+		if !math.IsInf(bestAhead, -1) {
+			return best, true
+		}
+	}
+	return want, false
+}
+
+// Move a circle of the radius at p out of the obstacles, (the least distance)
+func (t *Terrain) PushOut(p ik.Point, radius float64) ik.Point {
+	for pass := 0; pass < 2; pass++ {
+		for _, o := range t.Obstacles {
+			q, inside := o.Closest(p)
+			d := ik.Distance(p, q)
+			switch {
+			case inside:
+				out := q.Sub(p)
+				if d == 0 {
+					out = ik.Point{X: 1}
+				}
+				p = q.Add(out.SetMag(radius))
+			case d < radius:
+				p = q.Add(p.Sub(q).SetMag(radius))
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,143 @@
+package terrain
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// A wall across the middle, with a way around at the bottom, and a pond feet can't be put in
+func testTerrain() *Terrain {
+	t := TerrainNew(ik.Point{}, ik.Point{X: 1000, Y: 1000}, 20)
+	t.Obstacles = []Shape{
+		Wall{From: ik.Point{X: 500, Y: 0}, To: ik.Point{X: 500, Y: 700}, Width: 40},
+		Polygon{Points: []ik.Point{{X: 100, Y: 800}, {X: 200, Y: 800}, {X: 200, Y: 900}, {X: 100, Y: 900}}},
+	}
+	t.Unwalkable = []Shape{Circle{Center: ik.Point{X: 250, Y: 300}, Radius: 100}}
+	return t
+}
+
+func TestShapes(t *testing.T) {
+	tt := []struct {
+		shape  Shape
+		p      ik.Point
+		inside bool
+		dist   float64
+	}{
+		{Circle{Center: ik.Point{X: 0, Y: 0}, Radius: 10}, ik.Point{X: 4, Y: 0}, true, -6},
+		{Circle{Center: ik.Point{X: 0, Y: 0}, Radius: 10}, ik.Point{X: 0, Y: 15}, false, 5},
+		{Wall{ik.Point{X: 0, Y: 0}, ik.Point{X: 100, Y: 0}, 20}, ik.Point{X: 50, Y: 25}, false, 15},
+		{Wall{ik.Point{X: 0, Y: 0}, ik.Point{X: 100, Y: 0}, 20}, ik.Point{X: 105, Y: 0}, true, -5},
+		{Polygon{[]ik.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}}, ik.Point{X: 2, Y: 5}, true, -2},
+		{Polygon{[]ik.Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}}, ik.Point{X: 5, Y: 13}, false, 3},
+	}
+	for _, tc := range tt {
+		if _, inside := tc.shape.Closest(tc.p); inside != tc.inside {
+			t.Errorf("ERR: actual: %v,  expected: %v, (%v at %v)", inside, tc.inside, tc.shape, tc.p)
+		}
+		if d := SignedDistance(tc.shape, tc.p); math.Abs(d-tc.dist) > 1e-9 {
+			t.Errorf("ERR: actual: %v,  expected: %v, (%v at %v)", d, tc.dist, tc.shape, tc.p)
+		}
+	}
+}
+
+func TestFoothold(t *testing.T) {
+	ter := testTerrain()
+	// In the pond: the closest spot on the shore
+	p, ok := ter.Foothold(ik.Point{X: 300, Y: 300}, ik.Point{X: 1}, 80)
+	if !ok || !ter.Walkable(p) {
+		t.Fatalf("ERR: actual: %v, %v,  expected: a walkable foothold", p, ok)
+	}
+	if d := ik.Distance(p, ik.Point{X: 300, Y: 300}); d > 60 {
+		t.Errorf("ERR: actual: %v,  expected: at most %v", d, 60)
+	}
+	// Too far in
+	if p, ok := ter.Foothold(ik.Point{X: 250, Y: 300}, ik.Point{X: 1}, 50); ok {
+		t.Errorf("ERR: actual: %v,  expected: no foothold", p)
+	}
+	// Out of the wall
+	if p, ok := ter.Foothold(ik.Point{X: 505, Y: 200}, ik.Point{X: 1}, 50); !ok || !ter.Walkable(p) {
+		t.Errorf("ERR: actual: %v, %v,  expected: a walkable foothold", p, ok)
+	}
+	// In a puddle: the spot ahead, whichever way that is, (within the 60 degrees between the first ring's points)
+	ter.Unwalkable = append(ter.Unwalkable, Circle{Center: ik.Point{X: 800, Y: 300}, Radius: 5})
+	for _, heading := range []ik.Point{{X: 1}, {Y: -1}, {X: -1, Y: 1}} {
+		p, ok := ter.Foothold(ik.Point{X: 800, Y: 300}, heading, 80)
+		if ahead := p.Sub(ik.Point{X: 800, Y: 300}); !ok || ahead.Dot(heading) < 0.8*ahead.Mag()*heading.Mag() {
+			t.Errorf("ERR: actual: %v,  expected: ahead, along %v", p, heading)
+		}
+	}
+}
+
+func TestPushOut(t *testing.T) {
+	ter := testTerrain()
+	p := ter.PushOut(ik.Point{X: 490, Y: 300}, 15)
+	if c := ter.Clearance(p); c < 15-1e-9 {
+		t.Errorf("ERR: actual: %v,  expected: at least %v", c, 15)
+	}
+	if p.X > 500 {
+		t.Errorf("ERR: actual: %v,  expected: pushed back to the left", p)
+	}
+}
+
+func TestFindPath(t *testing.T) {
+	ter := testTerrain()
+	from, to := ik.Point{X: 300, Y: 100}, ik.Point{X: 700, Y: 100}
+	path, ok := ter.FindPath(from, to, 30)
+	if !ok {
+		t.Fatalf("ERR: actual: %v,  expected: a path", ok)
+	}
+	if path[len(path)-1] != to {
+		t.Errorf("ERR: actual: %v,  expected: %v", path[len(path)-1], to)
+	}
+	// Around the bottom end of the wall, and never through anything
+	lowest := 0.0
+	at := from
+	for _, p := range path {
+		if !ter.inSight(at, p, 30) {
+			t.Errorf("ERR: actual: blocked from %v to %v,  expected: a clear way", at, p)
+		}
+		lowest = max(lowest, p.Y)
+		at = p
+	}
+	if lowest < 700 {
+		t.Errorf("ERR: actual: %v,  expected: below %v", lowest, 700)
+	}
+
+	// Walled in: no way out
+	ter.Obstacles = append(ter.Obstacles, Wall{From: ik.Point{X: 0, Y: 700}, To: ik.Point{X: 1000, Y: 700}, Width: 40})
+	ter.Changed()
+	if path, ok := ter.FindPath(from, to, 30); ok {
+		t.Errorf("ERR: actual: %v,  expected: no path", path)
+	}
+}
+
+// Squeezed against the wall, (within the clearance), or pushed into it: still a way around
+func TestFindPathBlockedStart(t *testing.T) {
+	ter := testTerrain()
+	to := ik.Point{X: 700, Y: 100}
+	for _, from := range []ik.Point{{X: 465, Y: 100}, {X: 495, Y: 100}} {
+		if ter.Free(from, 30) {
+			t.Fatalf("ERR: actual: free at %v,  expected: blocked", from)
+		}
+		path, ok := ter.FindPath(from, to, 30)
+		if !ok || path[len(path)-1] != to {
+			t.Fatalf("ERR: actual: %v, %v,  expected: a path to %v from %v", path, ok, to, from)
+		}
+		// Out into the open first, then a clear way around the bottom end of the wall
+		if !ter.Free(path[0], 30) || ik.Distance(from, path[0]) > 3*ter.Cell {
+			t.Errorf("ERR: actual: %v,  expected: a free cell next to %v", path[0], from)
+		}
+		lowest := 0.0
+		for i := 1; i < len(path); i++ {
+			if !ter.inSight(path[i-1], path[i], 30) {
+				t.Errorf("ERR: actual: blocked from %v to %v,  expected: a clear way", path[i-1], path[i])
+			}
+			lowest = max(lowest, path[i].Y)
+		}
+		if lowest < 700 {
+			t.Errorf("ERR: actual: %v,  expected: below %v", lowest, 700)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/mpja69/moving_snakes/ik"
+	"github.com/mpja69/moving_snakes/steering"
+)
+
+// How the members of a world steer: the weights of the behaviors, and how far they look
+type FlockOptions struct {
+	Separation       float64
+	Alignment        float64
+	Cohesion         float64
+	Wander           float64
+	Attract          float64 // Towards the target, (e.g. the mouse pointer)
+	Contain          float64 // Within the world's area
+	NeighborRadius   float64
+	SeparationRadius float64 // From any joint of another body
+}
+
+var DefaultFlockOptions = FlockOptions{
+	Separation: 6, Alignment: 0.6, Cohesion: 0.2, Wander: 0.6, Attract: 0.2, Contain: 3,
+	NeighborRadius: 300, SeparationRadius: 250,
+}
+
+// A scene of many creatures, each steered as a boid: apart from the other bodies, along with and towards its
+// neighbors, and loosely towards the target. A World is a Creature itself, so it runs wherever one does
+type World struct {
+	Flock   FlockOptions
+	Collide bool // Push the bodies out of each other
+	members []*member
+	bodies  *steering.Grid // Every body joint
+	heads   *steering.Grid
+	min     ik.Point // The area to stay within
+	max     ik.Point
+	// Scratch buffers for the neighbor queries
+	others    []ik.Point
+	positions []ik.Point
+	vels      []ik.Point
+}
+
+type member struct {
+	creature Creature
+	vehicle  *steering.Vehicle
+}
+
+func WorldNew(min, max ik.Point) *World {
+	return &World{Flock: DefaultFlockOptions, Collide: true, bodies: steering.GridNew(DefaultFlockOptions.SeparationRadius),
+		heads: steering.GridNew(DefaultFlockOptions.NeighborRadius), min: min, max: max}
+}
+
+// Add a creature to the world. The seed makes its wandering repeatable
+func (w *World) Add(creature Creature, seed int64) {
+	head := creature.chains()[0].First()
+	v := steering.VehicleNew(head.Pos, 120, 240, seed)
+	v.Radius = head.Radius
+	w.members = append(w.members, &member{creature: creature, vehicle: v})
+}
+
+// Add n creatures at random places in the area
+func (w *World) Spawn(n int, seed int64, newCreature func(x, y int) Creature) {
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < n; i++ {
+		x := w.min.X + r.Float64()*(w.max.X-w.min.X)
+		y := w.min.Y + r.Float64()*(w.max.Y-w.min.Y)
+		w.Add(newCreature(int(x), int(y)), r.Int63())
+	}
+}
+
+func (w *World) update(target ik.Point, dt float64) {
+	f := w.Flock
+	// Index every body joint, (so creatures keep apart along the whole body, not just the heads)
+	w.bodies.Clear()
+	w.heads.Clear()
+	for i, m := range w.members {
+		for _, j := range m.creature.chains()[0].Joints {
+			w.bodies.Insert(i, j.Pos)
+		}
+		w.heads.Insert(i, m.creature.chains()[0].First().Pos)
+	}
+
+	for i, m := range w.members {
+		v := m.vehicle
+		v.Pos = m.creature.chains()[0].First().Pos
+		w.others, w.positions, w.vels = w.others[:0], w.positions[:0], w.vels[:0]
+		w.bodies.Near(v.Pos, f.SeparationRadius, func(id int, pos ik.Point) {
+			if id != i {
+				w.others = append(w.others, pos)
+			}
+		})
+		w.heads.Near(v.Pos, f.NeighborRadius, func(id int, pos ik.Point) {
+			if id != i {
+				w.positions = append(w.positions, pos)
+				w.vels = append(w.vels, w.members[id].vehicle.Vel)
+			}
+		})
+		v.Apply(v.Separate(w.others, f.SeparationRadius), f.Separation)
+		v.Apply(v.Align(w.vels), f.Alignment)
+		v.Apply(v.Cohere(w.positions), f.Cohesion)
+		v.Apply(v.Wander(200, 80, 0.3), f.Wander)
+		v.Apply(v.Seek(target), f.Attract)
+		v.Apply(v.Contain(w.min, w.max, 300), f.Contain)
+		v.Update(dt)
+		m.creature.update(v.Target(), dt)
+	}
+
+	if w.Collide {
+		w.collide()
+	}
+}
+
+// Push apart the bodies that overlap, (the limbs may cross)
+func (w *World) collide() {
+	bodies := make([]*ik.Chain, len(w.members))
+	for i, m := range w.members {
+		bodies[i] = m.creature.chains()[0]
+	}
+	ik.Collide(bodies, ik.DefaultCollisionOptions)
+}
+
+func (w *World) draw(screen *ebiten.Image) {
+	for _, m := range w.members {
+		m.creature.draw(screen)
+	}
+}
+
+func (w *World) sketch(s *Sketch) {
+	for _, m := range w.members {
+		m.creature.sketch(s)
+	}
+}
+
+// Random numbers a snapshot may have a vehicle draw again, (over three days of wandering at 60 ticks a second;
+// more is a broken file)
+const drawsMax = 1 << 24
+
+// The members, and their vehicles, (with how far along its random numbers the wandering is)
+func (w *World) visitState(v stateVisitor) {
+	for i, m := range w.members {
+		p := prefixed{v, "member" + strconv.Itoa(i) + "."}
+		m.creature.visitState(p)
+		p.point("vehicle.pos", &m.vehicle.Pos)
+		p.point("vehicle.vel", &m.vehicle.Vel)
+		wander := m.vehicle.WanderAngle()
+		p.float("vehicle.wander", &wander)
+		m.vehicle.SetWanderAngle(wander)
+		// The seed in two halves, (a float64 holds 53 bits)
+		seed, draws := m.vehicle.RandState()
+		hi, lo := float64(uint64(seed)>>32), float64(uint32(seed))
+		p.float("vehicle.seed.hi", &hi)
+		p.float("vehicle.seed.lo", &lo)
+		p.count("vehicle.draws", &draws, drawsMax)
+		m.vehicle.SetRandState(int64(uint64(hi)<<32|uint64(lo)), draws)
+	}
+}
+
+func (w *World) debugDraw(screen *ebiten.Image) {
+	for _, m := range w.members {
+		m.creature.debugDraw(screen)
+	}
+}
+
+// The chains of every creature, in turn
+func (w *World) chains() []*ik.Chain {
+	var chains []*ik.Chain
+	for _, m := range w.members {
+		chains = append(chains, m.creature.chains()...)
+	}
+	return chains
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mpja69/moving_snakes/ik"
+)
+
+// How often a head overlaps another body, over 10 seconds, (after the first 10, when the spawn has sorted itself out)
+func worldOverlaps(flock FlockOptions, seed int64) int {
+	w := WorldNew(ik.Point{}, ik.Point{X: WIDTH * FACTOR, Y: HEIGHT * FACTOR})
+	w.Flock = flock
+	w.Spawn(10, seed, func(x, y int) Creature { return LizardNew(x, y) })
+	sim := SimulatorNew(w, WaypointPath([]ik.Point{{X: WIDTH, Y: HEIGHT}}, 1))
+	overlaps := 0
+	for i := 0; i < 1200; i++ {
+		sim.Step()
+		if i < 600 {
+			continue
+		}
+		for a, m := range w.members {
+			head := m.creature.chains()[0].First()
+			for b, n := range w.members {
+				if a == b {
+					continue
+				}
+				for _, j := range n.creature.chains()[0].Joints {
+					if ik.Distance(head.Pos, j.Pos) < head.Radius+j.Radius {
+						overlaps++
+					}
+				}
+			}
+		}
+	}
+	return overlaps
+}
+
+// Over a few spawns, as a crowd is chaotic: one alone may be a lucky or unlucky one
+func TestWorldSeparation(t *testing.T) {
+	flock := DefaultFlockOptions
+	flock.Separation = 0
+	apart, together := 0, 0
+	for seed := int64(1); seed <= 4; seed++ {
+		apart += worldOverlaps(DefaultFlockOptions, seed)
+		together += worldOverlaps(flock, seed)
+	}
+	if apart*20 > together {
+		t.Errorf("ERR: actual: %v,  expected: far fewer than %v", apart, together)
+	}
+}